@@ -1,99 +1,157 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
+	"image/png"
+	"io"
 	"os"
-	"strconv"
-	"strings"
-)
 
-type Line struct {
-	Xa, Ya, Xb, Yb int
-}
+	"github.com/mechanical-fish/advent2021/five/vents"
+)
 
-func NewLine(xa, ya, xb, yb int) *Line {
-	return &Line{Xa: xa, Ya: ya, Xb: xb, Yb: yb}
-}
+func main() {
+	engine := flag.String("engine", "grid",
+		"grid rasterizes every line, sweep counts overlaps from the geometry")
+	useDiagonals := flag.Bool("diagonals", true,
+		"draw sloped lines too (part two), not just horizontal and vertical ones")
+	rasterName := flag.String("raster", "bresenham",
+		"how sloped lines cover points: bresenham, supercover or lattice")
+	minOverlap := flag.Int("min-overlap", 2,
+		"count points covered by at least this many lines")
+	histogram := flag.Bool("histogram", false,
+		"print how many points are covered by each number of lines")
+	lineStats := flag.Bool("line-stats", false,
+		"print how many overlap points each input line contributes")
+	at := flag.String("at", "",
+		"list the lines that cover this x,y point")
+	regions := flag.Bool("regions", false,
+		"list the bounding boxes of the connected overlap regions")
+	pgmFile := flag.String("pgm", "", "write a heatmap of the plane to this PGM file")
+	pngFile := flag.String("png", "", "write a heatmap of the plane to this PNG file")
+	svgFile := flag.String("svg", "", "draw the lines and their overlaps to this SVG file")
+	workers := flag.Int("parallel", 0,
+		"draw the grid with this many goroutines (0 draws it serially)")
+	format := flag.String("format", vents.FormatAuto,
+		"input format: auto, arrow (x1,y1 -> x2,y2), csv (x1,y1,x2,y2) or json")
+	flag.Parse()
+	raster, err := vents.ParseRaster(*rasterName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *minOverlap < 1 {
+		fmt.Fprintf(os.Stderr, "-min-overlap must be at least 1, not %d\n", *minOverlap)
+		os.Exit(2)
+	}
 
-func ParseLine(s string) *Line {
-	f := strings.Fields(s)
-	if f[1] != "->" {
-		panic("A line of input did not contain an arrow")
+	lines, err := vents.ReadLines(os.Stdin, *format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-	ptStrings := append(strings.Split(f[0], `,`), strings.Split(f[2], `,`)...)
-	pts := make([]int, len(ptStrings))
-	var err error
-	for i, ps := range ptStrings {
-		if pts[i], err = strconv.Atoi(ps); err != nil {
-			panic(err)
+	var plane *vents.Plane
+	var overlaps int
+	switch *engine {
+	case "grid":
+		plane = vents.NewPlane(*useDiagonals)
+		plane.Raster = raster
+		if *workers > 0 {
+			plane.AddLinesParallel(lines, *workers)
+		} else {
+			for _, n := range lines {
+				plane.AddLine(n)
+			}
+		}
+		overlaps = plane.Overlaps(*minOverlap)
+	case "sweep":
+		// the sweep engine only knows 45° lines, where the rules
+		// agree except for supercover's corner cells, and it only
+		// knows about pairs of lines
+		if raster == vents.Supercover || *minOverlap != 2 || *workers > 0 || *histogram || *lineStats ||
+			*regions || *pgmFile != "" || *pngFile != "" || *svgFile != "" {
+			fmt.Fprintln(os.Stderr, "the sweep engine only counts overlaps of 2 or more lines, without supercover, workers, stats or images")
+			os.Exit(2)
 		}
+		if overlaps, err = vents.CountOverlaps(lines, *useDiagonals); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown engine %q\n", *engine)
+		os.Exit(2)
 	}
-	return NewLine(pts[0], pts[1], pts[2], pts[3])
-}
-
-type Point struct {
-	X, Y int
-}
-
-func (n Line) PointsOn(useDiagonals bool) []Point {
-	result := make([]Point, 0, 2)
-	var dX, dY int = 0, 0
-	if n.Xa < n.Xb {
-		dX = 1
-	} else if n.Xa > n.Xb {
-		dX = -1
+	printOverlaps(overlaps, *minOverlap)
+	if *histogram {
+		vents.WriteHistogram(os.Stdout, plane.Histogram())
 	}
-	if n.Ya < n.Yb {
-		dY = 1
-	} else if n.Ya > n.Yb {
-		dY = -1
+	if *lineStats {
+		plane.WriteLineStats(os.Stdout, lines, *minOverlap)
 	}
-	if !useDiagonals && dX != 0 && dY != 0 {
-		return result
+	if *regions {
+		for _, r := range plane.OverlapRegions(*minOverlap) {
+			fmt.Printf("  %d points from %d,%d to %d,%d\n",
+				r.Points, r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+		}
 	}
-	var x, y int = n.Xa, n.Ya
-	for x != n.Xb || y != n.Yb {
-		result = append(result, Point{x, y})
-		x += dX
-		y += dY
+	if *at != "" {
+		// reuse the CSV parser by treating the point as a line to itself
+		n, err := vents.ParseCSVLine(*at + "," + *at)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "-at wants x,y: %v\n", err)
+			os.Exit(2)
+		}
+		pt := vents.Point{X: n.Xa, Y: n.Ya}
+		index := vents.NewIndex(lines, *useDiagonals, raster, vents.DefaultBucketSize)
+		covering := index.LinesAt(pt)
+		fmt.Printf("%d lines cover %d,%d\n", len(covering), pt.X, pt.Y)
+		for _, i := range covering {
+			fmt.Printf("  line %d, %v\n", i+1, lines[i])
+		}
+	}
+	if err := writeImages(plane, lines, *minOverlap, *pgmFile, *pngFile, *svgFile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-	return append(result, Point{n.Xb, n.Yb})
-}
-
-type Plane struct {
-	Grid [][]uint8
 }
 
-func NewPlane(xSize, ySize int) *Plane {
-	p := &Plane{
-		Grid: make([][]uint8, xSize),
-	}
-	for i := range p.Grid {
-		p.Grid[i] = make([]uint8, ySize)
+func printOverlaps(overlaps, min int) {
+	if min == 2 {
+		fmt.Printf("Found %d overlaps\n", overlaps)
+	} else {
+		fmt.Printf("Found %d points covered by %d or more lines\n", overlaps, min)
 	}
-	return p
 }
 
-func (p *Plane) AddLine(n *Line, useDiagonals bool) {
-	for _, pt := range n.PointsOn(useDiagonals) {
-		p.Grid[pt.X][pt.Y] = p.Grid[pt.X][pt.Y] + 1
+// writeImages writes whichever of the heatmaps and the SVG drawing
+// have been given a file name
+func writeImages(p *vents.Plane, lines []*vents.Line, min int, pgmFile, pngFile, svgFile string) error {
+	if pgmFile != "" || pngFile != "" {
+		img, err := p.Heatmap()
+		if err != nil {
+			return err
+		}
+		if err := writeFile(pgmFile, func(w io.Writer) error { return vents.WritePGM(w, img) }); err != nil {
+			return err
+		}
+		if err := writeFile(pngFile, func(w io.Writer) error { return png.Encode(w, img) }); err != nil {
+			return err
+		}
 	}
+	return writeFile(svgFile, func(w io.Writer) error { return p.WriteSVG(w, lines, min) })
 }
 
-func main() {
-	scanner := bufio.NewScanner(os.Stdin)
-	plane := NewPlane(1000, 1000)
-	for scanner.Scan() {
-		plane.AddLine(ParseLine(scanner.Text()), true)
+func writeFile(name string, write func(io.Writer) error) error {
+	if name == "" {
+		return nil
 	}
-	overlaps := 0
-	for _, row := range plane.Grid {
-		for _, o := range row {
-			if o >= 2 {
-				overlaps++
-			}
-		}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
 	}
-	fmt.Printf("Found %d overlaps\n", overlaps)
+	return f.Close()
 }
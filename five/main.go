@@ -1,120 +1,60 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
 	"os"
-	"strconv"
-	"strings"
-)
-
-type Line struct {
-	Xa, Ya, Xb, Yb int
-}
-
-func NewLine(xa, ya, xb, yb int) *Line {
-	return &Line{Xa: xa, Ya: ya, Xb: xb, Yb: yb}
-}
-
-func ParseLine(s string) *Line {
-	f := strings.Fields(s)
-	if f[1] != "->" {
-		panic("A line of input did not contain an arrow")
-	}
-	ptStrings := append(strings.Split(f[0], `,`), strings.Split(f[2], `,`)...)
-	pts := make([]int, len(ptStrings))
-	var err error
-	for i, ps := range ptStrings {
-		if pts[i], err = strconv.Atoi(ps); err != nil {
-			panic(err)
-		}
-	}
-	return NewLine(pts[0], pts[1], pts[2], pts[3])
-}
-
-func (n Line) IsHorizontal() bool {
-	return n.Ya == n.Yb
-}
-
-func (n Line) IsVertical() bool {
-	return n.Xa == n.Xb
-}
-
-type Point struct {
-	X int
-	Y int
-}
 
-func (n Line) PointsOn() []Point {
-	result := make([]Point, 0, 2)
-	if n.IsHorizontal() {
-		xS, xL := n.Xa, n.Xb
-		if xS > xL {
-			xS, xL = xL, xS
-		}
-		for x := xS; x <= xL; x++ {
-			result = append(result, Point{x, n.Ya})
-		}
-	} else if n.IsVertical() {
-		yS, yL := n.Ya, n.Yb
-		if yS > yL {
-			yS, yL = yL, yS
-		}
-		for y := yS; y <= yL; y++ {
-			result = append(result, Point{n.Xa, y})
-		}
-	} else {
-		// rearrange the endpoints so the first point is on the left
-		xL, yL, xR, yR := n.Xa, n.Ya, n.Xb, n.Yb
-		if xL > xR {
-			xL, xR, yL, yR = xR, xL, yR, yL
-		}
-		var slope int = 1
-		if yR < yL {
-			slope = -1
-		}
-		y := yL
-		for x := xL; x <= xR; x++ {
-			result = append(result, Point{x, y})
-			y += slope
-		}
-	}
-	return result
-}
-
-type Plane struct {
-	Grid [][]uint8
-}
-
-func NewPlane(xSize, ySize int) *Plane {
-	p := &Plane{
-		Grid: make([][]uint8, xSize),
-	}
-	for i := range p.Grid {
-		p.Grid[i] = make([]uint8, ySize)
-	}
-	return p
-}
-
-func (p *Plane) AddLine(n *Line) {
-	for _, pt := range n.PointsOn() {
-		p.Grid[pt.X][pt.Y] = p.Grid[pt.X][pt.Y] + 1
-	}
-}
+	"github.com/mechanical-fish/advent2021/five/vents"
+)
 
 func main() {
-	scanner := bufio.NewScanner(os.Stdin)
-	plane := NewPlane(1000, 1000)
-	for scanner.Scan() {
-		plane.AddLine(ParseLine(scanner.Text()))
-	}
-	overlaps := 0
-	for _, row := range plane.Grid {
-		for _, o := range row {
-			if o >= 2 {
-				overlaps++
-			}
-		}
+	useDiagonals := flag.Bool("diagonals", true,
+		"draw sloped lines too (part two), not just horizontal and vertical ones")
+	rasterName := flag.String("raster", "bresenham",
+		"how sloped lines cover points: bresenham, supercover or lattice")
+	minOverlap := flag.Int("min-overlap", 2,
+		"count points covered by at least this many lines")
+	histogram := flag.Bool("histogram", false,
+		"print how many points are covered by each number of lines")
+	lineStats := flag.Bool("line-stats", false,
+		"print how many overlap points each input line contributes")
+	format := flag.String("format", vents.FormatAuto,
+		"input format: auto, arrow (x1,y1 -> x2,y2), csv (x1,y1,x2,y2) or json")
+	flag.Parse()
+	raster, err := vents.ParseRaster(*rasterName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *minOverlap < 1 {
+		fmt.Fprintf(os.Stderr, "-min-overlap must be at least 1, not %d\n", *minOverlap)
+		os.Exit(2)
+	}
+
+	lines, err := vents.ReadLines(os.Stdin, *format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	plane := vents.NewPlane(*useDiagonals)
+	plane.Raster = raster
+	for _, n := range lines {
+		plane.AddLine(n)
+	}
+	printOverlaps(plane.Overlaps(*minOverlap), *minOverlap)
+	if *histogram {
+		vents.WriteHistogram(os.Stdout, plane.Histogram())
+	}
+	if *lineStats {
+		plane.WriteLineStats(os.Stdout, lines, *minOverlap)
+	}
+}
+
+func printOverlaps(overlaps, min int) {
+	if min == 2 {
+		fmt.Printf("Found %d overlaps\n", overlaps)
+	} else {
+		fmt.Printf("Found %d points covered by %d or more lines\n", overlaps, min)
 	}
-	fmt.Printf("Found %d overlaps\n", overlaps)
 }
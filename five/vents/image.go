@@ -0,0 +1,110 @@
+package vents
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+)
+
+// maxPixels keeps a stray coordinate from turning into a multi-gigabyte
+// image; unlike the Plane, an image has to cover the whole bounding box
+const maxPixels = 1 << 26
+
+// Bounds returns the smallest rectangle holding every covered point,
+// with ok=false if the plane is empty
+func (p *Plane) Bounds() (min, max Point, ok bool) {
+	p.Each(func(pt Point, _ int) {
+		if !ok {
+			min, max, ok = pt, pt, true
+			return
+		}
+		if pt.X < min.X {
+			min.X = pt.X
+		}
+		if pt.Y < min.Y {
+			min.Y = pt.Y
+		}
+		if pt.X > max.X {
+			max.X = pt.X
+		}
+		if pt.Y > max.Y {
+			max.Y = pt.Y
+		}
+	})
+	return min, max, ok
+}
+
+// Heatmap draws the plane one pixel per point, with x to the right and
+// y down as in the puzzle. Uncovered points are black and the most
+// covered point is white.
+func (p *Plane) Heatmap() (*image.Gray, error) {
+	min, max, ok := p.Bounds()
+	if !ok {
+		return nil, fmt.Errorf("the plane is empty")
+	}
+	w, h := max.X-min.X+1, max.Y-min.Y+1
+	if w <= 0 || h <= 0 || w > maxPixels/h {
+		return nil, fmt.Errorf("a %d x %d heatmap is too large", w, h)
+	}
+	top := 0
+	p.Each(func(_ Point, count int) {
+		if count > top {
+			top = count
+		}
+	})
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	p.Each(func(pt Point, count int) {
+		// keep a single line visible even when the top count is huge
+		level := 255
+		if top > 1 {
+			level = 32 + 223*(count-1)/(top-1)
+		}
+		img.Pix[img.PixOffset(pt.X-min.X, pt.Y-min.Y)] = uint8(level)
+	})
+	return img, nil
+}
+
+// WritePGM writes img as a binary (P5) portable graymap
+func WritePGM(w io.Writer, img *image.Gray) error {
+	b := img.Bounds()
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "P5\n%d %d\n255\n", b.Dx(), b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		bw.Write(img.Pix[img.PixOffset(b.Min.X, y):img.PixOffset(b.Max.X, y)])
+	}
+	return bw.Flush()
+}
+
+// WriteSVG draws every line on top of the cells covered by at least min
+// lines, which are filled in red. Each point is a unit square, so a
+// line runs between the centers of its end squares.
+func (p *Plane) WriteSVG(w io.Writer, lines []*Line, min int) error {
+	lo, hi, ok := p.Bounds()
+	if !ok {
+		return fmt.Errorf("the plane is empty")
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="%d %d %d %d">`+"\n",
+		lo.X-1, lo.Y-1, hi.X-lo.X+3, hi.Y-lo.Y+3)
+	fmt.Fprintf(bw, `<rect x="%d" y="%d" width="%d" height="%d" fill="white"/>`+"\n",
+		lo.X-1, lo.Y-1, hi.X-lo.X+3, hi.Y-lo.Y+3)
+	fmt.Fprintln(bw, `<g fill="red" fill-opacity="0.6">`)
+	p.Each(func(pt Point, count int) {
+		if count >= min {
+			fmt.Fprintf(bw, `<rect x="%d" y="%d" width="1" height="1"><title>%d,%d: %d lines</title></rect>`+"\n",
+				pt.X, pt.Y, pt.X, pt.Y, count)
+		}
+	})
+	fmt.Fprintln(bw, `</g>`)
+	fmt.Fprintln(bw, `<g stroke="black" stroke-width="0.2" stroke-linecap="round">`)
+	// lines run through the centers of their end cells
+	center := func(v int) float64 { return float64(v) + 0.5 }
+	for _, n := range lines {
+		fmt.Fprintf(bw, `<line x1="%g" y1="%g" x2="%g" y2="%g"/>`+"\n",
+			center(n.Xa), center(n.Ya), center(n.Xb), center(n.Yb))
+	}
+	fmt.Fprintln(bw, `</g>`)
+	fmt.Fprintln(bw, `</svg>`)
+	return bw.Flush()
+}
@@ -0,0 +1,20 @@
+package vents
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteSVGNegativeCenters(t *testing.T) {
+	lines := []*Line{NewLine(-3, -3, 2, -3)}
+	p := NewPlane(true)
+	p.AddLine(lines[0])
+	var b strings.Builder
+	if err := p.WriteSVG(&b, lines, 2); err != nil {
+		t.Fatal(err)
+	}
+	want := `<line x1="-2.5" y1="-2.5" x2="2.5" y2="-2.5"/>`
+	if !strings.Contains(b.String(), want) {
+		t.Errorf("SVG has no %s:\n%s", want, b.String())
+	}
+}
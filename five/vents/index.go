@@ -0,0 +1,128 @@
+package vents
+
+import "sort"
+
+// DefaultBucketSize suits puzzle-sized inputs: a few hundred lines over
+// a thousand-unit square
+const DefaultBucketSize = 16
+
+// An Index answers "which lines cover this point?" without looking at
+// every line. The plane is cut into square buckets, and each bucket
+// lists the lines that pass through it, so a query only has to check
+// the lines in one bucket.
+type Index struct {
+	Lines        []*Line
+	UseDiagonals bool
+	Raster       Raster
+
+	bucketSize int
+	buckets    map[Point][]int
+}
+
+// NewIndex indexes lines the way a Plane with the same settings would
+// draw them. Building it costs one pass over every point of every line.
+func NewIndex(lines []*Line, useDiagonals bool, r Raster, bucketSize int) *Index {
+	if bucketSize < 1 {
+		bucketSize = DefaultBucketSize
+	}
+	idx := &Index{
+		Lines:        lines,
+		UseDiagonals: useDiagonals,
+		Raster:       r,
+		bucketSize:   bucketSize,
+		buckets:      make(map[Point][]int),
+	}
+	for i, n := range lines {
+		// lines are added in order, so a bucket already holding this
+		// line has it at the end of its list
+		for _, pt := range n.PointsOn(useDiagonals, r) {
+			b := idx.bucketOf(pt)
+			if ls := idx.buckets[b]; len(ls) == 0 || ls[len(ls)-1] != i {
+				idx.buckets[b] = append(ls, i)
+			}
+		}
+	}
+	return idx
+}
+
+func (idx *Index) bucketOf(pt Point) Point {
+	floor := func(v int) int {
+		b := v / idx.bucketSize
+		if v%idx.bucketSize < 0 {
+			b--
+		}
+		return b
+	}
+	return Point{floor(pt.X), floor(pt.Y)}
+}
+
+// LinesAt returns the positions in Lines of the lines covering pt, in
+// input order
+func (idx *Index) LinesAt(pt Point) []int {
+	var result []int
+	for _, i := range idx.buckets[idx.bucketOf(pt)] {
+		if idx.Lines[i].Covers(pt, idx.UseDiagonals, idx.Raster) {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// A Region is a connected patch of overlap points, where points touch
+// if they are neighbours horizontally, vertically or diagonally
+type Region struct {
+	Min, Max Point // the bounding box, inclusive
+	Points   int
+}
+
+// OverlapRegions finds the connected patches of points covered by at
+// least min lines, largest first
+func (p *Plane) OverlapRegions(min int) []Region {
+	var regions []Region
+	seen := make(map[Point]bool)
+	p.Each(func(start Point, count int) {
+		if count < min || seen[start] {
+			return
+		}
+		r := Region{Min: start, Max: start}
+		seen[start] = true
+		queue := []Point{start}
+		for len(queue) > 0 {
+			pt := queue[0]
+			queue = queue[1:]
+			r.Points++
+			if pt.X < r.Min.X {
+				r.Min.X = pt.X
+			}
+			if pt.Y < r.Min.Y {
+				r.Min.Y = pt.Y
+			}
+			if pt.X > r.Max.X {
+				r.Max.X = pt.X
+			}
+			if pt.Y > r.Max.Y {
+				r.Max.Y = pt.Y
+			}
+			for dx := -1; dx <= 1; dx++ {
+				for dy := -1; dy <= 1; dy++ {
+					next := Point{pt.X + dx, pt.Y + dy}
+					if !seen[next] && p.At(next) >= min {
+						seen[next] = true
+						queue = append(queue, next)
+					}
+				}
+			}
+		}
+		regions = append(regions, r)
+	})
+	sort.Slice(regions, func(i, j int) bool {
+		if regions[i].Points != regions[j].Points {
+			return regions[i].Points > regions[j].Points
+		}
+		if regions[i].Min.Y != regions[j].Min.Y {
+			return regions[i].Min.Y < regions[j].Min.Y
+		}
+		return regions[i].Min.X < regions[j].Min.X
+	})
+	return regions
+}
@@ -0,0 +1,111 @@
+package vents
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Input formats for ReadLines
+const (
+	FormatAuto  = "auto"
+	FormatArrow = "arrow" // x1,y1 -> x2,y2 on each line, as in the puzzle
+	FormatCSV   = "csv"   // x1,y1,x2,y2 on each line
+	FormatJSON  = "json"  // [[x1,y1,x2,y2], ...]
+)
+
+// parseInts converts each of fields to an int, naming the first one
+// that isn't
+func parseInts(fields []string) ([]int, error) {
+	result := make([]int, len(fields))
+	for i, f := range fields {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", f)
+		}
+		result[i] = n
+	}
+	return result, nil
+}
+
+// ParseCSVLine parses a line in the form x1,y1,x2,y2
+func ParseCSVLine(s string) (*Line, error) {
+	f := strings.Split(s, `,`)
+	if len(f) != 4 {
+		return nil, fmt.Errorf("expected x1,y1,x2,y2 but found %d fields", len(f))
+	}
+	pts, err := parseInts(f)
+	if err != nil {
+		return nil, err
+	}
+	return NewLine(pts[0], pts[1], pts[2], pts[3]), nil
+}
+
+// detectFormat guesses the format from the start of the input
+func detectFormat(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte(`[`)):
+		return FormatJSON
+	case bytes.Contains(trimmed, []byte(`->`)):
+		return FormatArrow
+	}
+	return FormatCSV
+}
+
+// ReadLines reads every line from r. Blank lines are skipped, and a
+// bad line is reported by its line number (or, in JSON, by its
+// position in the array).
+func ReadLines(r io.Reader, format string) ([]*Line, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if format == FormatAuto {
+		format = detectFormat(data)
+	}
+	var parse func(string) (*Line, error)
+	switch format {
+	case FormatJSON:
+		return parseJSONLines(data)
+	case FormatArrow:
+		parse = ParseLine
+	case FormatCSV:
+		parse = ParseCSVLine
+	default:
+		return nil, fmt.Errorf("unknown input format %q", format)
+	}
+	var lines []*Line
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		n, err := parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		lines = append(lines, n)
+	}
+	return lines, scanner.Err()
+}
+
+func parseJSONLines(data []byte) ([]*Line, error) {
+	var segments [][]int
+	if err := json.Unmarshal(data, &segments); err != nil {
+		return nil, fmt.Errorf("expected a JSON array of [x1,y1,x2,y2] segments: %v", err)
+	}
+	lines := make([]*Line, len(segments))
+	for i, s := range segments {
+		if len(s) != 4 {
+			return nil, fmt.Errorf("segment %d: expected [x1,y1,x2,y2] but found %d numbers", i+1, len(s))
+		}
+		lines[i] = NewLine(s[0], s[1], s[2], s[3])
+	}
+	return lines, nil
+}
@@ -0,0 +1,123 @@
+// Package vents maps the hydrothermal vent lines of day five onto a
+// plane and counts where they overlap. Both day-five programs are thin
+// command lines over it.
+package vents
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Line struct {
+	Xa, Ya, Xb, Yb int
+}
+
+func NewLine(xa, ya, xb, yb int) *Line {
+	return &Line{Xa: xa, Ya: ya, Xb: xb, Yb: yb}
+}
+
+// ParseLine parses a line in the puzzle's x1,y1 -> x2,y2 form
+func ParseLine(s string) (*Line, error) {
+	f := strings.Fields(s)
+	if len(f) != 3 || f[1] != "->" {
+		return nil, fmt.Errorf("expected x1,y1 -> x2,y2 but found %q", s)
+	}
+	from, to := strings.Split(f[0], `,`), strings.Split(f[2], `,`)
+	if len(from) != 2 || len(to) != 2 {
+		return nil, fmt.Errorf("expected x,y points on both sides of the arrow in %q", s)
+	}
+	pts, err := parseInts(append(from, to...))
+	if err != nil {
+		return nil, err
+	}
+	return NewLine(pts[0], pts[1], pts[2], pts[3]), nil
+}
+
+func (n Line) String() string {
+	return fmt.Sprintf("%d,%d -> %d,%d", n.Xa, n.Ya, n.Xb, n.Yb)
+}
+
+func (n Line) IsHorizontal() bool {
+	return n.Ya == n.Yb
+}
+
+func (n Line) IsVertical() bool {
+	return n.Xa == n.Xb
+}
+
+// IsSloped is true of every line that is neither horizontal nor
+// vertical, whether or not it is at 45°
+func (n Line) IsSloped() bool {
+	return !n.IsHorizontal() && !n.IsVertical()
+}
+
+type Point struct {
+	X, Y int
+}
+
+// PointsOn lists the points on the line. Sloped lines are skipped
+// unless useDiagonals is set, and r decides which points they cover.
+func (n Line) PointsOn(useDiagonals bool, r Raster) []Point {
+	result := make([]Point, 0, 2)
+	var dX, dY int = 0, 0
+	if n.Xa < n.Xb {
+		dX = 1
+	} else if n.Xa > n.Xb {
+		dX = -1
+	}
+	if n.Ya < n.Yb {
+		dY = 1
+	} else if n.Ya > n.Yb {
+		dY = -1
+	}
+	if dX != 0 && dY != 0 {
+		// stepping both coordinates together only works at 45°, so
+		// leave sloped lines to the raster rule
+		if !useDiagonals {
+			return result
+		}
+		return r.Points(n.Xa, n.Ya, n.Xb, n.Yb)
+	}
+	var x, y int = n.Xa, n.Ya
+	for x != n.Xb || y != n.Yb {
+		result = append(result, Point{x, y})
+		x += dX
+		y += dY
+	}
+	return append(result, Point{n.Xb, n.Yb})
+}
+
+// Covers reports whether PointsOn would include pt. Horizontal,
+// vertical and 45° lines, and lattice rasters, are answered with
+// arithmetic; anything else has to be rasterized.
+func (n Line) Covers(pt Point, useDiagonals bool, r Raster) bool {
+	xLo, xHi, yLo, yHi := n.Xa, n.Xb, n.Ya, n.Yb
+	if xLo > xHi {
+		xLo, xHi = xHi, xLo
+	}
+	if yLo > yHi {
+		yLo, yHi = yHi, yLo
+	}
+	// every raster rule stays inside the line's bounding box
+	if xLo > pt.X || pt.X > xHi || yLo > pt.Y || pt.Y > yHi {
+		return false
+	}
+	if !n.IsSloped() {
+		return true
+	}
+	if !useDiagonals {
+		return false
+	}
+	dx, dy := n.Xb-n.Xa, n.Yb-n.Ya
+	if r == Lattice || (r == Bresenham && abs(dx) == abs(dy)) {
+		// on the line exactly, and a whole number of steps along it
+		g := gcd(abs(dx), abs(dy))
+		return (pt.X-n.Xa)*dy == (pt.Y-n.Ya)*dx && (pt.X-n.Xa)%(dx/g) == 0
+	}
+	for _, q := range r.Points(n.Xa, n.Ya, n.Xb, n.Yb) {
+		if q == pt {
+			return true
+		}
+	}
+	return false
+}
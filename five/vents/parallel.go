@@ -0,0 +1,138 @@
+package vents
+
+import (
+	"runtime"
+	"sync"
+)
+
+// AddLinesParallel draws lines onto the plane with several goroutines.
+// The rows the lines span are cut into one band per worker, aligned to
+// tile boundaries, and each worker rasterizes just its own band of
+// every line into a private Plane. Since no tile belongs to two bands,
+// merging the shards only moves tiles, and the result is exactly what
+// AddLine would have drawn one line at a time.
+func (p *Plane) AddLinesParallel(lines []*Line, workers int) {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	if len(lines) == 0 {
+		return
+	}
+	yMin, yMax := lines[0].Ya, lines[0].Ya
+	for _, n := range lines {
+		for _, y := range []int{n.Ya, n.Yb} {
+			if y < yMin {
+				yMin = y
+			}
+			if y > yMax {
+				yMax = y
+			}
+		}
+	}
+	tMin, _ := tileOf(yMin)
+	tMax, _ := tileOf(yMax)
+	tilesPerBand := (tMax - tMin + workers) / workers
+
+	shards := make([]*Plane, workers)
+	var wg sync.WaitGroup
+	for w := range shards {
+		shard := NewPlane(p.UseDiagonals)
+		shard.Raster = p.Raster
+		shards[w] = shard
+		yLo := (tMin + w*tilesPerBand) * tileSize
+		yHi := yLo + tilesPerBand*tileSize - 1
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, n := range lines {
+				for _, pt := range n.pointsInRows(shard.UseDiagonals, shard.Raster, yLo, yHi) {
+					shard.Add(pt)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, shard := range shards {
+		for idx, t := range shard.tiles {
+			mine, ok := p.tiles[idx]
+			if !ok {
+				p.tiles[idx] = t
+				continue
+			}
+			for ox := range t {
+				for oy, count := range t[ox] {
+					sum := int(mine[ox][oy]) + int(count)
+					if sum > MaxCount {
+						sum = MaxCount
+					}
+					mine[ox][oy] = uint16(sum)
+				}
+			}
+		}
+	}
+}
+
+// pointsInRows is PointsOn restricted to yLo <= y <= yHi. Lines that
+// step one point at a time (everything but Bresenham or supercover
+// slopes other than 45°) are clipped arithmetically, so a long line
+// costs each band only its own share.
+func (n Line) pointsInRows(useDiagonals bool, r Raster, yLo, yHi int) []Point {
+	if n.Ya < yLo && n.Yb < yLo || n.Ya > yHi && n.Yb > yHi {
+		return nil
+	}
+	dx, dy := n.Xb-n.Xa, n.Yb-n.Ya
+	if n.IsSloped() && (r == Supercover || (r == Bresenham && abs(dx) != abs(dy))) {
+		var result []Point
+		for _, pt := range n.PointsOn(useDiagonals, r) {
+			if yLo <= pt.Y && pt.Y <= yHi {
+				result = append(result, pt)
+			}
+		}
+		return result
+	}
+	if dy == 0 {
+		return n.PointsOn(useDiagonals, r)
+	}
+	if n.IsSloped() && !useDiagonals {
+		return nil
+	}
+	// step (sx, sy) from the first end; y = Ya + sy*t for t in 0..steps
+	steps := gcd(abs(dx), abs(dy))
+	if r == Bresenham || !n.IsSloped() {
+		steps = abs(dy)
+	}
+	sx, sy := dx/steps, dy/steps
+	var tLo, tHi int
+	if sy > 0 {
+		tLo, tHi = (yLo-n.Ya+sy-1)/sy, (yHi-n.Ya)/sy
+	} else {
+		tLo, tHi = (n.Ya-yHi-sy-1)/-sy, (n.Ya-yLo)/-sy
+	}
+	if tLo < 0 {
+		tLo = 0
+	}
+	if tHi > steps {
+		tHi = steps
+	}
+	if tLo > tHi {
+		return nil
+	}
+	result := make([]Point, 0, tHi-tLo+1)
+	for t := tLo; t <= tHi; t++ {
+		result = append(result, Point{n.Xa + sx*t, n.Ya + sy*t})
+	}
+	return result
+}
+
+// Equal reports whether two planes have the same count at every point
+func (p *Plane) Equal(q *Plane) bool {
+	same := true
+	p.Each(func(pt Point, count int) {
+		same = same && q.At(pt) == count
+	})
+	q.Each(func(pt Point, count int) {
+		same = same && p.At(pt) == count
+	})
+	return same
+}
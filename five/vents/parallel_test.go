@@ -0,0 +1,71 @@
+package vents
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// slopedLines makes count lines between any two points in a size x size
+// box centered on the origin, so most of them are neither straight nor
+// 45° and every raster rule has to clip them to the workers' bands
+func slopedLines(rng *rand.Rand, count, size int) []*Line {
+	lines := make([]*Line, count)
+	for i := range lines {
+		lines[i] = NewLine(rng.Intn(size)-size/2, rng.Intn(size)-size/2,
+			rng.Intn(size)-size/2, rng.Intn(size)-size/2)
+	}
+	return lines
+}
+
+func serialPlane(lines []*Line, r Raster) *Plane {
+	p := NewPlane(true)
+	p.Raster = r
+	for _, n := range lines {
+		p.AddLine(n)
+	}
+	return p
+}
+
+func TestParallelMatchesSerial(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, r := range []Raster{Bresenham, Supercover, Lattice} {
+		for round := 0; round < 50; round++ {
+			// boxes of a few tiles, so lines cross band edges
+			lines := slopedLines(rng, 1+rng.Intn(60), 8+rng.Intn(6*tileSize))
+			if round%2 == 1 {
+				lines = append(lines, randomLines(rng, 20, 4*tileSize)...)
+			}
+			want := serialPlane(lines, r)
+			for _, workers := range []int{1, 2, 3, 7} {
+				got := NewPlane(true)
+				got.Raster = r
+				got.AddLinesParallel(lines, workers)
+				if !got.Equal(want) {
+					t.Fatalf("raster %d, %d workers: parallel plane differs from serial for %v",
+						r, workers, lines)
+				}
+			}
+		}
+	}
+}
+
+func benchLines() []*Line {
+	return slopedLines(rand.New(rand.NewSource(1)), 500, 1000)
+}
+
+func BenchmarkAddLine(b *testing.B) {
+	lines := benchLines()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		serialPlane(lines, Bresenham)
+	}
+}
+
+func BenchmarkAddLinesParallel(b *testing.B) {
+	lines := benchLines()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := NewPlane(true)
+		p.AddLinesParallel(lines, 0)
+	}
+}
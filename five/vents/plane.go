@@ -0,0 +1,151 @@
+package vents
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// The Plane is a sparse map of square tiles, so its memory grows with
+// the area the lines actually cover rather than with their bounding
+// box, and coordinates may be as large or as negative as an int allows.
+const tileSize = 64
+
+// Counters saturate at MaxCount rather than wrapping back to zero
+const MaxCount = math.MaxUint16
+
+type tile [tileSize][tileSize]uint16
+
+type Plane struct {
+	Raster       Raster
+	UseDiagonals bool
+	tiles        map[Point]*tile
+}
+
+// NewPlane makes an empty plane. Sloped lines are only drawn on it if
+// useDiagonals is set, which is the difference between the puzzle's
+// two parts.
+func NewPlane(useDiagonals bool) *Plane {
+	return &Plane{UseDiagonals: useDiagonals, tiles: make(map[Point]*tile)}
+}
+
+// tileOf splits a coordinate into the index of its tile and its offset
+// within that tile, rounding down so negative coordinates work too
+func tileOf(v int) (int, int) {
+	t := v / tileSize
+	if v%tileSize < 0 {
+		t--
+	}
+	return t, v - t*tileSize
+}
+
+// At returns how many lines cover pt
+func (p *Plane) At(pt Point) int {
+	tx, ox := tileOf(pt.X)
+	ty, oy := tileOf(pt.Y)
+	if t, ok := p.tiles[Point{tx, ty}]; ok {
+		return int(t[ox][oy])
+	}
+	return 0
+}
+
+func (p *Plane) Add(pt Point) {
+	tx, ox := tileOf(pt.X)
+	ty, oy := tileOf(pt.Y)
+	t, ok := p.tiles[Point{tx, ty}]
+	if !ok {
+		t = new(tile)
+		p.tiles[Point{tx, ty}] = t
+	}
+	if t[ox][oy] < MaxCount {
+		t[ox][oy]++
+	}
+}
+
+func (p *Plane) AddLine(n *Line) {
+	for _, pt := range n.PointsOn(p.UseDiagonals, p.Raster) {
+		p.Add(pt)
+	}
+}
+
+// Each calls fn for every point covered by at least one line, in no
+// particular order
+func (p *Plane) Each(fn func(pt Point, count int)) {
+	for idx, t := range p.tiles {
+		for ox := range t {
+			for oy, count := range t[ox] {
+				if count > 0 {
+					fn(Point{idx.X*tileSize + ox, idx.Y*tileSize + oy}, int(count))
+				}
+			}
+		}
+	}
+}
+
+// Overlaps counts the points covered by at least min lines
+func (p *Plane) Overlaps(min int) int {
+	overlaps := 0
+	p.Each(func(_ Point, count int) {
+		if count >= min {
+			overlaps++
+		}
+	})
+	return overlaps
+}
+
+// Histogram maps each number of covering lines to how many points are
+// covered by exactly that many. Points no line covers are left out.
+func (p *Plane) Histogram() map[int]int {
+	h := make(map[int]int)
+	p.Each(func(_ Point, count int) {
+		h[count]++
+	})
+	return h
+}
+
+// A LineStat says how much of the plane one line covers, and how many
+// of those points are overlaps
+type LineStat struct {
+	Points   int
+	Overlaps int
+}
+
+// LineStats reports on a line that has already been added to the plane
+func (p *Plane) LineStats(n *Line, min int) LineStat {
+	var s LineStat
+	for _, pt := range n.PointsOn(p.UseDiagonals, p.Raster) {
+		s.Points++
+		if p.At(pt) >= min {
+			s.Overlaps++
+		}
+	}
+	return s
+}
+
+// WriteHistogram prints a Histogram as a table
+func WriteHistogram(w io.Writer, h map[int]int) {
+	counts := make([]int, 0, len(h))
+	for count := range h {
+		counts = append(counts, count)
+	}
+	sort.Ints(counts)
+	fmt.Fprintln(w, "Lines\tPoints")
+	for _, count := range counts {
+		suffix := ""
+		if count == MaxCount {
+			suffix = " (or more)"
+		}
+		fmt.Fprintf(w, "%d%s\t%d\n", count, suffix, h[count])
+	}
+}
+
+// WriteLineStats prints the LineStats of every line, numbered from 1
+// in input order
+func (p *Plane) WriteLineStats(w io.Writer, lines []*Line, min int) {
+	for i, n := range lines {
+		s := p.LineStats(n, min)
+		fmt.Fprintf(w, "  line %d, %v: %d points, %d overlaps\n",
+			i+1, n, s.Points, s.Overlaps)
+	}
+}
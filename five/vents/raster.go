@@ -0,0 +1,119 @@
+package vents
+
+import "fmt"
+
+// A Raster is a rule for which grid points a sloped line covers.
+// Horizontal and vertical lines cover the same points under every
+// rule, and so do 45° lines except under Supercover.
+type Raster int
+
+const (
+	// Bresenham picks the one point per column (or per row, for steep
+	// lines) closest to the true line
+	Bresenham Raster = iota
+	// Supercover takes every cell the line passes through, including
+	// both neighbours wherever it passes exactly through a corner
+	Supercover
+	// Lattice only takes the points that lie exactly on the line
+	Lattice
+)
+
+func ParseRaster(s string) (Raster, error) {
+	switch s {
+	case "bresenham":
+		return Bresenham, nil
+	case "supercover":
+		return Supercover, nil
+	case "lattice":
+		return Lattice, nil
+	}
+	return Bresenham, fmt.Errorf("unknown raster rule %q (want bresenham, supercover or lattice)", s)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func sign(n int) int {
+	if n < 0 {
+		return -1
+	} else if n > 0 {
+		return 1
+	}
+	return 0
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// Points lists the points from (xa,ya) to (xb,yb) under the rule. Each
+// loop is bounded by the length of the line, so it always ends.
+func (r Raster) Points(xa, ya, xb, yb int) []Point {
+	dx, dy := abs(xb-xa), abs(yb-ya)
+	sx, sy := sign(xb-xa), sign(yb-ya)
+	switch r {
+	case Supercover:
+		// walk cell by cell, deciding at each step whether the line
+		// leaves through a vertical edge, a horizontal edge or a corner
+		result := make([]Point, 0, dx+dy+1)
+		x, y := xa, ya
+		result = append(result, Point{x, y})
+		for ix, iy := 0, 0; ix < dx || iy < dy; {
+			switch d := (1+2*ix)*dy - (1+2*iy)*dx; {
+			case d == 0:
+				result = append(result, Point{x + sx, y}, Point{x, y + sy})
+				x, y = x+sx, y+sy
+				ix, iy = ix+1, iy+1
+			case d < 0:
+				x += sx
+				ix++
+			default:
+				y += sy
+				iy++
+			}
+			result = append(result, Point{x, y})
+		}
+		return result
+	case Lattice:
+		g := gcd(dx, dy)
+		if g == 0 {
+			return []Point{{xa, ya}}
+		}
+		stepX, stepY := (xb-xa)/g, (yb-ya)/g
+		result := make([]Point, g+1)
+		for i := range result {
+			result[i] = Point{xa + i*stepX, ya + i*stepY}
+		}
+		return result
+	default:
+		n := dx
+		if dy > n {
+			n = dy
+		}
+		result := make([]Point, 0, n+1)
+		x, y := xa, ya
+		err := dx - dy
+		for {
+			result = append(result, Point{x, y})
+			if x == xb && y == yb {
+				return result
+			}
+			e2 := 2 * err
+			if e2 > -dy {
+				err -= dy
+				x += sx
+			}
+			if e2 < dx {
+				err += dx
+				y += sy
+			}
+		}
+	}
+}
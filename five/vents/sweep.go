@@ -0,0 +1,244 @@
+package vents
+
+import (
+	"fmt"
+	"sort"
+)
+
+// The sweep engine counts overlap points straight from the segment
+// geometry, without visiting every point on every line. It only
+// understands the lines the puzzle promises: horizontal, vertical and
+// 45 degree diagonals.
+//
+// Every such line lies on a "carrier": the full row, column or
+// diagonal through it. Lines on the same carrier can only overlap
+// collinearly, and those overlaps are found by merging intervals along
+// the carrier. Lines on different carriers meet in at most one point,
+// and those crossings are found with a sweep over x that only compares
+// segments whose x ranges are open at the same time.
+
+// a family of parallel carriers
+type family int
+
+const (
+	horizontal family = iota // key is y, position is x
+	vertical                 // key is x, position is y
+	rising                   // key is x-y, position is x
+	falling                  // key is x+y, position is x
+)
+
+type carrier struct {
+	fam family
+	key int
+}
+
+type interval struct {
+	lo, hi int
+}
+
+func (iv interval) length() int {
+	return iv.hi - iv.lo + 1
+}
+
+// a piece is a maximal run of a carrier covered by at least one line
+type piece struct {
+	carrier
+	interval
+}
+
+func (p piece) xRange() (int, int) {
+	if p.fam == vertical {
+		return p.key, p.key
+	}
+	return p.lo, p.hi
+}
+
+// carrierOf returns the carrier of a line and the interval it covers
+// along it, or ok=false if the line is not horizontal, vertical or 45°
+func carrierOf(n *Line) (c carrier, iv interval, ok bool) {
+	dx, dy := n.Xb-n.Xa, n.Yb-n.Ya
+	lo, hi := n.Xa, n.Xb
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	switch {
+	case dy == 0:
+		return carrier{horizontal, n.Ya}, interval{lo, hi}, true
+	case dx == 0:
+		lo, hi = n.Ya, n.Yb
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		return carrier{vertical, n.Xa}, interval{lo, hi}, true
+	case dx == dy:
+		return carrier{rising, n.Xa - n.Ya}, interval{lo, hi}, true
+	case dx == -dy:
+		return carrier{falling, n.Xa + n.Ya}, interval{lo, hi}, true
+	}
+	return carrier{}, interval{}, false
+}
+
+// carriersThrough returns the four carriers through pt and pt's
+// position along each
+func carriersThrough(pt Point) [4]piece {
+	return [4]piece{
+		{carrier{horizontal, pt.Y}, interval{pt.X, pt.X}},
+		{carrier{vertical, pt.X}, interval{pt.Y, pt.Y}},
+		{carrier{rising, pt.X - pt.Y}, interval{pt.X, pt.X}},
+		{carrier{falling, pt.X + pt.Y}, interval{pt.X, pt.X}},
+	}
+}
+
+// mergeIntervals returns the union of ivs, and the parts of it covered
+// by two or more of them, both sorted
+func mergeIntervals(ivs []interval) (union, multi []interval) {
+	type edge struct{ at, delta int }
+	edges := make([]edge, 0, 2*len(ivs))
+	for _, iv := range ivs {
+		edges = append(edges, edge{iv.lo, 1}, edge{iv.hi + 1, -1})
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].at < edges[j].at })
+	depth := 0
+	for i := 0; i < len(edges); {
+		at, before := edges[i].at, depth
+		for ; i < len(edges) && edges[i].at == at; i++ {
+			depth += edges[i].delta
+		}
+		if before < 1 && depth >= 1 {
+			union = append(union, interval{at, 0})
+		} else if before >= 1 && depth < 1 {
+			union[len(union)-1].hi = at - 1
+		}
+		if before < 2 && depth >= 2 {
+			multi = append(multi, interval{at, 0})
+		} else if before >= 2 && depth < 2 {
+			multi[len(multi)-1].hi = at - 1
+		}
+	}
+	return union, multi
+}
+
+func containsPos(ivs []interval, pos int) bool {
+	i := sort.Search(len(ivs), func(i int) bool { return ivs[i].hi >= pos })
+	return i < len(ivs) && ivs[i].lo <= pos
+}
+
+// crossing returns the lattice point where two pieces on different
+// carriers meet, if they do
+func crossing(a, b piece) (Point, bool) {
+	if a.fam > b.fam {
+		a, b = b, a
+	}
+	var pt Point
+	switch {
+	case a.fam == horizontal && b.fam == vertical:
+		pt = Point{b.key, a.key}
+	case a.fam == horizontal && b.fam == rising:
+		pt = Point{a.key + b.key, a.key}
+	case a.fam == horizontal && b.fam == falling:
+		pt = Point{b.key - a.key, a.key}
+	case a.fam == vertical && b.fam == rising:
+		pt = Point{a.key, a.key - b.key}
+	case a.fam == vertical && b.fam == falling:
+		pt = Point{a.key, b.key - a.key}
+	case a.fam == rising && b.fam == falling:
+		// x-y=a.key and x+y=b.key only meet on the lattice if the
+		// keys have the same parity
+		if (a.key+b.key)%2 != 0 {
+			return pt, false
+		}
+		pt = Point{(a.key + b.key) / 2, (b.key - a.key) / 2}
+	default:
+		return pt, false
+	}
+	return pt, a.covers(pt) && b.covers(pt)
+}
+
+func (p piece) covers(pt Point) bool {
+	for _, c := range carriersThrough(pt) {
+		if c.carrier == p.carrier {
+			return p.lo <= c.lo && c.lo <= p.hi
+		}
+	}
+	return false
+}
+
+// CountOverlaps returns the number of points covered by at least two
+// of the lines, skipping sloped lines unless useDiagonals is set. It
+// fails on sloped lines that aren't at 45°, but only if they are drawn.
+func CountOverlaps(lines []*Line, useDiagonals bool) (int, error) {
+	byCarrier := make(map[carrier][]interval)
+	for _, n := range lines {
+		if !useDiagonals && n.IsSloped() {
+			continue
+		}
+		c, iv, ok := carrierOf(n)
+		if !ok {
+			return 0, fmt.Errorf("the sweep engine cannot handle %v, which is not at 45°", n)
+		}
+		byCarrier[c] = append(byCarrier[c], iv)
+	}
+
+	// collinear overlaps are counted directly from their lengths
+	overlaps := 0
+	multis := make(map[carrier][]interval)
+	var pieces []piece
+	for c, ivs := range byCarrier {
+		union, multi := mergeIntervals(ivs)
+		for _, iv := range union {
+			pieces = append(pieces, piece{c, iv})
+		}
+		for _, iv := range multi {
+			overlaps += iv.length()
+		}
+		if len(multi) > 0 {
+			multis[c] = multi
+		}
+	}
+
+	// crossings between carriers. A crossing on no collinear overlap
+	// is a new overlap point. A crossing where collinear overlaps on k
+	// carriers meet was counted k times above, so k-1 of those come
+	// back off. Several carriers may cross at one point, so each point
+	// is only looked at once.
+	sort.Slice(pieces, func(i, j int) bool {
+		li, _ := pieces[i].xRange()
+		lj, _ := pieces[j].xRange()
+		return li < lj
+	})
+	seen := make(map[Point]bool)
+	var active []piece
+	for _, p := range pieces {
+		lo, _ := p.xRange()
+		open := active[:0]
+		for _, q := range active {
+			if _, hi := q.xRange(); hi >= lo {
+				open = append(open, q)
+			}
+		}
+		active = open
+		for _, q := range active {
+			if q.fam == p.fam {
+				continue
+			}
+			pt, ok := crossing(p, q)
+			if !ok || seen[pt] {
+				continue
+			}
+			seen[pt] = true
+			k := 0
+			for _, c := range carriersThrough(pt) {
+				if containsPos(multis[c.carrier], c.lo) {
+					k++
+				}
+			}
+			if k == 0 {
+				overlaps++
+			} else {
+				overlaps -= k - 1
+			}
+		}
+		active = append(active, p)
+	}
+	return overlaps, nil
+}
@@ -0,0 +1,129 @@
+package vents
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// gridOverlaps counts overlaps the original way, by rasterizing
+func gridOverlaps(lines []*Line, useDiagonals bool, r Raster) int {
+	plane := NewPlane(useDiagonals)
+	plane.Raster = r
+	for _, n := range lines {
+		plane.AddLine(n)
+	}
+	return plane.Overlaps(2)
+}
+
+// randomLines makes count horizontal, vertical and 45° lines starting
+// inside a size x size box centered on the origin. Each is up to half
+// the box long, so small boxes give lots of overlaps.
+func randomLines(rng *rand.Rand, count, size int) []*Line {
+	lines := make([]*Line, count)
+	for i := range lines {
+		xa, ya := rng.Intn(size)-size/2, rng.Intn(size)-size/2
+		length := rng.Intn(size / 2)
+		dx, dy := 0, 0
+		switch rng.Intn(4) {
+		case 0:
+			dx = 1
+		case 1:
+			dy = 1
+		case 2:
+			dx, dy = 1, 1
+		default:
+			dx, dy = 1, -1
+		}
+		if rng.Intn(2) == 0 {
+			dx, dy = -dx, -dy
+		}
+		lines[i] = NewLine(xa, ya, xa+dx*length, ya+dy*length)
+	}
+	return lines
+}
+
+func TestSweepCases(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []*Line
+		want  int
+	}{
+		{
+			name: "collinear overlaps",
+			lines: []*Line{
+				NewLine(0, 0, 5, 0),
+				NewLine(3, 0, 8, 0),
+				NewLine(10, 0, 4, 0),
+			},
+			// 3..5 under the first two, 4..8 under the last two
+			want: 6,
+		},
+		{
+			name: "three-way crossing",
+			lines: []*Line{
+				NewLine(0, 0, 4, 4),
+				NewLine(0, 4, 4, 0),
+				NewLine(2, 0, 2, 4),
+			},
+			want: 1,
+		},
+		{
+			name: "rising and falling lines crossing between lattice points",
+			lines: []*Line{
+				NewLine(0, 0, 3, 3),
+				NewLine(0, 1, 3, -2),
+			},
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		got, err := CountOverlaps(tt.lines, true)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: sweep found %d overlaps, want %d", tt.name, got, tt.want)
+		}
+		if grid := gridOverlaps(tt.lines, true, Bresenham); grid != tt.want {
+			t.Errorf("%s: grid found %d overlaps, want %d", tt.name, grid, tt.want)
+		}
+	}
+}
+
+func TestSweepMatchesGrid(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for round := 0; round < 2000; round++ {
+		lines := randomLines(rng, 1+rng.Intn(40), 8+rng.Intn(40))
+		useDiagonals := rng.Intn(4) != 0
+		want := gridOverlaps(lines, useDiagonals, Bresenham)
+		got, err := CountOverlaps(lines, useDiagonals)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			for _, n := range lines {
+				t.Log(n)
+			}
+			t.Fatalf("round %d (diagonals %v): sweep found %d overlaps, grid found %d",
+				round, useDiagonals, got, want)
+		}
+	}
+}
+
+func TestSweepSkipsSlopedLines(t *testing.T) {
+	lines := []*Line{
+		NewLine(0, 0, 4, 0),
+		NewLine(2, -2, 2, 2),
+		NewLine(0, 0, 3, 7),
+	}
+	got, err := CountOverlaps(lines, false)
+	if err != nil {
+		t.Fatalf("without diagonals: %v", err)
+	}
+	if got != 1 {
+		t.Errorf("without diagonals: sweep found %d overlaps, want 1", got)
+	}
+	if _, err := CountOverlaps(lines, true); err == nil {
+		t.Error("with diagonals: a line that is not at 45° should be an error")
+	}
+}
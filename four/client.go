@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// A Client is one player in a Game, talking to it over HTTP and the
+// event WebSocket like any other client would. It keeps its own copy
+// of the card so it can decide for itself when to shout "bingo!".
+type Client struct {
+	BaseURL string
+	ID      int
+	Wins    []Event // win announcements seen so far, for any card
+
+	grid   [][]int
+	marked [][]bool
+	events *wsConn
+}
+
+// JoinGame registers a card with the server at baseURL (for example
+// http://127.0.0.1:8021) and subscribes to its events
+func JoinGame(baseURL string, rows []string) (*Client, error) {
+	c := &Client{BaseURL: baseURL}
+	for _, row := range rows {
+		nums, err := parseRow(row)
+		if err != nil {
+			return nil, err
+		}
+		c.grid = append(c.grid, nums)
+		c.marked = append(c.marked, make([]bool, len(nums)))
+	}
+	resp, err := http.Post(baseURL+"/cards", "text/plain",
+		strings.NewReader(strings.Join(rows, "\n")))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("registering card: %s: %s", resp.Status, msg)
+	}
+	var reply struct{ Card int }
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return nil, err
+	}
+	c.ID = reply.Card
+	wsURL := "ws" + strings.TrimPrefix(baseURL, "http") +
+		"/events?card=" + strconv.Itoa(c.ID)
+	if c.events, err = wsDial(wsURL); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// CallNumber asks the server at baseURL to call the next number
+func CallNumber(baseURL string) (int, error) {
+	resp, err := http.Post(baseURL+"/call", "", nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("calling a number: %s", resp.Status)
+	}
+	var reply struct{ Number int }
+	err = json.NewDecoder(resp.Body).Decode(&reply)
+	return reply.Number, err
+}
+
+func (c *Client) next() (Event, error) {
+	var e Event
+	msg, err := c.events.ReadMessage()
+	if err == nil {
+		err = json.Unmarshal(msg, &e)
+	}
+	if e.Type == "win" {
+		c.Wins = append(c.Wins, e)
+	}
+	return e, err
+}
+
+// Await reads events until it has seen the call at time t and every
+// mark that call makes on this card. The server sends the marks right
+// after the call, and the client can count them from its own grid.
+func (c *Client) Await(t int) error {
+	var n int
+	for {
+		e, err := c.next()
+		if err != nil {
+			return err
+		}
+		if e.Type == "over" {
+			return fmt.Errorf("game ended before call %d", t)
+		}
+		if e.Type == "call" && e.Time == t {
+			n = e.Number
+			break
+		}
+	}
+	expected := 0
+	for _, r := range c.grid {
+		for _, m := range r {
+			if m == n {
+				expected++
+			}
+		}
+	}
+	for expected > 0 {
+		e, err := c.next()
+		if err != nil {
+			return err
+		}
+		if e.Type == "mark" && e.Card == c.ID {
+			c.marked[e.Row][e.Col] = true
+			expected--
+		}
+	}
+	return nil
+}
+
+// HasBingo reports whether the client's own marks make a full row or
+// column
+func (c *Client) HasBingo() bool {
+	for i := range c.marked {
+		row, col := true, true
+		for j := range c.marked {
+			row = row && c.marked[i][j]
+			col = col && c.marked[j][i]
+		}
+		if row || col {
+			return true
+		}
+	}
+	return false
+}
+
+// Claim shouts "bingo!" and returns the server's verdict
+func (c *Client) Claim() (valid bool, score int, err error) {
+	resp, err := http.Post(fmt.Sprintf("%s/bingo?card=%d", c.BaseURL, c.ID), "", nil)
+	if err != nil {
+		return false, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusConflict {
+		return false, 0, fmt.Errorf("claiming card %d: %s", c.ID, resp.Status)
+	}
+	var reply struct {
+		Valid bool
+		Score int
+	}
+	err = json.NewDecoder(resp.Body).Decode(&reply)
+	return reply.Valid, reply.Score, err
+}
+
+func (c *Client) Close() error {
+	return c.events.Close()
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// playDemo runs a whole game in-process: it serves the game on a
+// random loopback port, joins one client per card, calls numbers on
+// demand and lets each client claim as soon as it sees a bingo. Every
+// verdict has to agree with the card's analytic WinTime, and a
+// premature claim has to be rejected, or playDemo returns an error.
+func playDemo(caller *Caller, scorer Scorer, players *Players, out io.Writer) error {
+	game := NewGame(caller, scorer)
+	l, err := ListenLocal("127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: game.Handler()}
+	go server.Serve(l)
+	defer server.Close()
+	baseURL := "http://" + l.Addr().String()
+	fmt.Fprintf(out, "Demo game on %s\n", baseURL)
+
+	clients := make([]*Client, len(players.Cards))
+	for i, card := range players.Cards {
+		rows := make([]string, len(card.Grid))
+		for j, r := range card.Grid {
+			nums := make([]string, len(r))
+			for k, n := range r {
+				nums[k] = strconv.Itoa(n)
+			}
+			rows[j] = strings.Join(nums, " ")
+		}
+		if clients[i], err = JoinGame(baseURL, rows); err != nil {
+			return err
+		}
+		defer clients[i].Close()
+	}
+	if len(clients) > 0 {
+		if valid, _, err := clients[0].Claim(); err != nil || valid {
+			return fmt.Errorf("a claim before any calls was accepted (err %v)", err)
+		}
+		fmt.Fprintf(out, "  Card %d's early claim was rejected\n", clients[0].ID)
+	}
+
+	claimed := make([]bool, len(clients))
+	remaining := len(clients)
+	for t := 0; remaining > 0 && t < len(caller.Sequence); t++ {
+		n, err := CallNumber(baseURL)
+		if err != nil {
+			return err
+		}
+		for i, c := range clients {
+			if err := c.Await(t); err != nil {
+				return err
+			}
+			if claimed[i] || !c.HasBingo() {
+				continue
+			}
+			valid, score, err := c.Claim()
+			if err != nil {
+				return err
+			}
+			card := players.Cards[i]
+			if !valid || t != card.WinTime {
+				return fmt.Errorf("card %d claimed at call %d (valid %v) but WinTime is %d",
+					c.ID, t+1, valid, card.WinTime+1)
+			}
+			if want := scorer.Score(card); score != want {
+				return fmt.Errorf("card %d scored %d but should score %d", c.ID, score, want)
+			}
+			fmt.Fprintf(out, "  Call %d (%d): card %d claims bingo, score %d\n",
+				t+1, n, c.ID, score)
+			claimed[i] = true
+			remaining--
+		}
+	}
+	for i, card := range players.Cards {
+		if card.HasWon() && !claimed[i] {
+			return fmt.Errorf("card %d should have won after %d numbers but never claimed",
+				i+1, card.WinTime+1)
+		}
+	}
+	fmt.Fprintf(out, "All %d claims matched the analytic win times\n",
+		len(players.Cards)-remaining)
+	return nil
+}
+
+// readTestInput reads the puzzle's example, plus a card that wins on
+// the 5th call, when WinTime is len(Grid)-1, and one that never wins
+func readTestInput(t *testing.T) (*Caller, *Players) {
+	example, err := os.ReadFile("testinput.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	extra := `
+7 4 9 5 11
+30 31 32 33 34
+35 36 37 38 39
+40 41 42 43 44
+45 46 47 48 49
+
+50 51 52 53 54
+55 56 57 58 59
+60 61 62 63 64
+65 66 67 68 69
+70 71 72 73 74
+`
+	caller, players, err := ReadInput(strings.NewReader(string(example) + extra))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if players.Count() != 5 {
+		t.Fatalf("read %d cards, want 5", players.Count())
+	}
+	return caller, players
+}
+
+func TestWinTimes(t *testing.T) {
+	_, players := readTestInput(t)
+	for i, want := range []struct {
+		won     bool
+		winTime int
+		score   int
+	}{
+		{true, 13, 2192},
+		{true, 14, 1924},
+		{true, 11, 4512},
+		{true, 4, (30 + 49) * 10 * 11}, // the unmarked 30..49, times 11
+		{false, never, 0},
+	} {
+		c := players.Cards[i]
+		if c.HasWon() != want.won || c.WinTime != want.winTime || c.Score() != want.score {
+			t.Errorf("card %d: HasWon %v, WinTime %d, score %d; want %v, %d, %d",
+				i+1, c.HasWon(), c.WinTime, c.Score(), want.won, want.winTime, want.score)
+		}
+	}
+}
+
+func TestPlayDemo(t *testing.T) {
+	caller, players := readTestInput(t)
+	for _, name := range ScorerNames() {
+		var out strings.Builder
+		if err := playDemo(caller, Scorers[name], players, &out); err != nil {
+			t.Errorf("scoring %s: %v\n%s", name, err, out.String())
+		}
+	}
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"regexp"
 	"strconv"
@@ -57,7 +60,7 @@ func (c *Caller) String() string {
 // A Card is a single bingo card
 type Card struct {
 	Grid    [][]int
-	WinTime int
+	WinTime int // never, if no row or column ever gets filled
 
 	caller       *Caller
 	nextEmptyRow int
@@ -77,7 +80,7 @@ func NewCard(caller *Caller, row string) (*Card, error) {
 	}
 	c := &Card{
 		Grid:          make([][]int, len(nums)),
-		WinTime:       0,
+		WinTime:       never,
 		caller:        caller,
 		nextEmptyRow:  0,
 		winTimeForRow: make([]int, len(nums)),
@@ -87,10 +90,15 @@ func NewCard(caller *Caller, row string) (*Card, error) {
 	return c, nil
 }
 
+// never is the winning time of a row or column holding a number that
+// isn't in the call sequence, and of a card with no row or column that
+// ever wins
+const never = math.MaxInt
+
 // if t is a better winning time than the one we already know,
 // record t as the best winning time
 func (c *Card) setWinTime(t int) {
-	if c.WinTime < len(c.Grid) || t < c.WinTime {
+	if t < c.WinTime {
 		c.WinTime = t
 	}
 }
@@ -99,7 +107,12 @@ func (c *Card) addRowNums(nums []int) {
 	c.Grid[c.nextEmptyRow] = make([]int, len(c.Grid))
 	for i, n := range nums {
 		c.Grid[c.nextEmptyRow][i] = n
-		t := c.caller.TimesByNum[n]
+		t, ok := c.caller.TimesByNum[n]
+		if !ok {
+			// a number that is never called never gets marked, so its
+			// row and column never win
+			t = never
+		}
 		if t > c.winTimeForRow[c.nextEmptyRow] {
 			c.winTimeForRow[c.nextEmptyRow] = t
 		}
@@ -144,22 +157,9 @@ func (c Card) IsComplete() bool {
 	return c.nextEmptyRow == len(c.Grid)
 }
 
+// Score uses the rule from the puzzle; see Scorer for the others
 func (c Card) Score() int {
-	// if this card has not won its score is 0
-	if c.WinTime < len(c.Grid) {
-		return 0
-	}
-	// add up unmarked numbers
-	sum := 0
-	for _, r := range c.Grid {
-		for _, n := range r {
-			if c.caller.TimesByNum[n] > c.WinTime {
-				sum += n
-			}
-		}
-	}
-	// multiply by the final number called
-	return sum * c.caller.NumsByTime[c.WinTime]
+	return UnmarkedTimesLast{}.Score(&c)
 }
 
 // Players are a collection of cards
@@ -191,17 +191,18 @@ var CardRowRe = regexp.MustCompile(`\A\s?\d+\s+\d`)
 // the bingo cards end with a blank line
 var CardEndRe = regexp.MustCompile(`\A\z`)
 
-func main() {
+// ReadInput reads the call sequence and the cards that follow it
+func ReadInput(r io.Reader) (*Caller, *Players, error) {
 	caller := NewCaller()
 	players := NewPlayers()
 	var card *Card
 	var err error
-	scanner := bufio.NewScanner(os.Stdin)
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		row := scanner.Text()
 		if CallerRe.MatchString(row) {
 			if err = caller.ParseInput(row); err != nil {
-				panic(err)
+				return nil, nil, err
 			}
 		} else if CardRowRe.MatchString(row) {
 			if card == nil {
@@ -210,7 +211,7 @@ func main() {
 				err = card.AddRow(row)
 			}
 			if err != nil {
-				panic(err)
+				return nil, nil, err
 			}
 		} else if CardEndRe.MatchString(row) {
 			if card != nil {
@@ -224,27 +225,91 @@ func main() {
 	if card != nil {
 		players.Add(card)
 	}
+	return caller, players, scanner.Err()
+}
+
+func main() {
+	scoreName := flag.String("score", "standard",
+		"scoring rule: "+strings.Join(ScorerNames(), ", "))
+	scoreExpr := flag.String("score-expr", "",
+		"custom scoring expression, e.g. 'unmarked*last + 10*diags'")
+	renderName := flag.String("render", "",
+		"draw the first and last winners as they win: brackets or ansi")
+	serveAddr := flag.String("serve", "",
+		"play a live game on this loopback address, e.g. 127.0.0.1:8021")
+	interval := flag.Duration("interval", 0,
+		"with -serve, call a number this often instead of on POST /call")
+	flag.Parse()
+
+	scorer, ok := Scorers[*scoreName]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown scoring rule %q\n", *scoreName)
+		os.Exit(2)
+	}
+	if *scoreExpr != "" {
+		expr, err := ParseExpr(*scoreExpr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		scorer = expr
+	}
+	var style RenderStyle
+	if *renderName != "" {
+		var err error
+		if style, err = ParseRenderStyle(*renderName); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	caller, players, err := ReadInput(os.Stdin)
+	if err != nil {
+		panic(err)
+	}
+	if *serveAddr != "" {
+		// only the call sequence matters here, clients bring their own cards
+		if err := NewGame(caller, scorer).Serve(*serveAddr, *interval); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 	fmt.Println(caller)
 
 	fmt.Printf("Found %d cards\n", players.Count())
-	var winningCard int = 0
+	var winningCard int = -1
 	var winTime int
-	var losingestCard int = 0
+	var losingestCard int = -1
 	var latestWinTime int
 	for i, c := range players.Cards {
+		if !c.HasWon() {
+			fmt.Printf("  Card %d never wins\n", i+1)
+			continue
+		}
 		fmt.Printf("  Card %d wins after %d numbers with score %d\n",
-			i+1, c.WinTime+1, c.Score())
-		if winTime == 0 || c.WinTime < winTime {
+			i+1, c.WinTime+1, scorer.Score(c))
+		if winningCard < 0 || c.WinTime < winTime {
 			winningCard = i
 			winTime = c.WinTime
 		}
-		if latestWinTime == 0 || c.WinTime >= latestWinTime {
+		if losingestCard < 0 || c.WinTime >= latestWinTime {
 			losingestCard = i
 			latestWinTime = c.WinTime
 		}
 	}
+	if winningCard < 0 {
+		fmt.Println("No card wins")
+		return
+	}
 	fmt.Printf("Card %d is the overall winner after %d numbers\n with score %d\n",
-		winningCard+1, winTime+1, players.Cards[winningCard].Score())
+		winningCard+1, winTime+1, scorer.Score(players.Cards[winningCard]))
 	fmt.Printf("Card %d will win last, after %d numbers\n with score %d\n",
-		losingestCard+1, latestWinTime+1, players.Cards[losingestCard].Score())
+		losingestCard+1, latestWinTime+1, scorer.Score(players.Cards[losingestCard]))
+	if *renderName != "" {
+		fmt.Printf("\nCard %d when it wins:\n%s", winningCard+1,
+			players.Cards[winningCard].Render(winTime, style))
+		fmt.Printf("\nCard %d when it wins:\n%s", losingestCard+1,
+			players.Cards[losingestCard].Render(latestWinTime, style))
+	}
 }
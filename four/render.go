@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A RenderStyle decides how Card.Render marks cells
+type RenderStyle int
+
+const (
+	// Brackets puts [n] around marked numbers, {n} around the numbers
+	// in the winning row or column, and *n* around the number that
+	// completed it. It survives being pasted into an issue.
+	Brackets RenderStyle = iota
+	// ANSI uses terminal colors: green for marked, yellow for the
+	// winning line, and red for the number that completed it.
+	ANSI
+)
+
+func ParseRenderStyle(s string) (RenderStyle, error) {
+	switch s {
+	case "brackets":
+		return Brackets, nil
+	case "ansi":
+		return ANSI, nil
+	}
+	return Brackets, fmt.Errorf("unknown render style %q (want brackets or ansi)", s)
+}
+
+const (
+	ansiReset   = "\x1b[0m"
+	ansiMarked  = "\x1b[32m"
+	ansiWinLine = "\x1b[1;33m"
+	ansiWinNum  = "\x1b[1;31m"
+)
+
+// Render draws the card as it looks once the number at time t has been
+// called. If the card has won by then, the row(s) and column(s) that
+// completed at WinTime are highlighted along with the winning number.
+func (c Card) Render(t int, style RenderStyle) string {
+	width := 1
+	for _, r := range c.Grid {
+		for _, n := range r {
+			if w := len(strconv.Itoa(n)); w > width {
+				width = w
+			}
+		}
+	}
+
+	won := c.HasWon() && t >= c.WinTime
+	winNum := c.caller.NumsByTime[c.WinTime]
+	var b strings.Builder
+	for i, r := range c.Grid {
+		for j, n := range r {
+			if j > 0 {
+				b.WriteByte(' ')
+			}
+			num := fmt.Sprintf("%*d", width, n)
+			onWinLine := won &&
+				(c.winTimeForRow[i] == c.WinTime || c.winTimeForCol[j] == c.WinTime)
+			var pre, post string
+			switch {
+			case !c.IsMarked(n, t):
+				pre, post = " ", " "
+			case onWinLine && n == winNum:
+				pre, post = "*", "*"
+				if style == ANSI {
+					pre, post = " "+ansiWinNum, ansiReset+" "
+				}
+			case onWinLine:
+				pre, post = "{", "}"
+				if style == ANSI {
+					pre, post = " "+ansiWinLine, ansiReset+" "
+				}
+			default:
+				pre, post = "[", "]"
+				if style == ANSI {
+					pre, post = " "+ansiMarked, ansiReset+" "
+				}
+			}
+			b.WriteString(pre + num + post)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderBrackets(t *testing.T) {
+	caller, players, err := ReadInput(strings.NewReader("1,2,3\n\n1 2\n3 4\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caller.Sequence[1] != 2 || players.Count() != 1 {
+		t.Fatalf("read %v and %d cards", caller.Sequence, players.Count())
+	}
+	card := players.Cards[0]
+	// the top row wins when 2 is called, at t=1
+	for _, c := range []struct {
+		t    int
+		want string
+	}{
+		{0, "[1]  2 \n 3   4 \n"},
+		{1, "{1} *2*\n 3   4 \n"},
+		{2, "{1} *2*\n[3]  4 \n"},
+	} {
+		if got := card.Render(c.t, Brackets); got != c.want {
+			t.Errorf("t=%d: got\n%s\nwant\n%s", c.t, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A Scorer computes the score of a Card at the moment it wins. Cards
+// that never win should score 0 under every rule.
+type Scorer interface {
+	Score(c *Card) int
+}
+
+// UnmarkedTimesLast is the rule from the puzzle: the sum of the
+// unmarked numbers multiplied by the number that completed the card.
+type UnmarkedTimesLast struct{}
+
+func (UnmarkedTimesLast) Score(c *Card) int {
+	if !c.HasWon() {
+		return 0
+	}
+	return c.unmarkedSum(c.WinTime) * c.caller.NumsByTime[c.WinTime]
+}
+
+// MarkedSum scores a card by adding up the numbers that were marked
+// when it won.
+type MarkedSum struct{}
+
+func (MarkedSum) Score(c *Card) int {
+	if !c.HasWon() {
+		return 0
+	}
+	return c.markedSum(c.WinTime)
+}
+
+// CallsToWin scores a card by the number of calls it needed, so lower
+// is better.
+type CallsToWin struct{}
+
+func (CallsToWin) Score(c *Card) int {
+	if !c.HasWon() {
+		return 0
+	}
+	return c.WinTime + 1
+}
+
+// PatternBonus adds a weighted bonus to a base score for every pattern
+// that is complete at the moment the card wins. A card that wins with
+// a row and a column simultaneously gets both bonuses.
+type PatternBonus struct {
+	Base     Scorer
+	Row      int
+	Column   int
+	Diagonal int
+	Corners  int
+}
+
+func (p PatternBonus) Score(c *Card) int {
+	if !c.HasWon() {
+		return 0
+	}
+	score := 0
+	if p.Base != nil {
+		score = p.Base.Score(c)
+	}
+	pats := c.patternsAt(c.WinTime)
+	return score + p.Row*pats.rows + p.Column*pats.cols +
+		p.Diagonal*pats.diags + p.Corners*pats.corners
+}
+
+// Scorers are the built-in rules, by the name used on the command line
+var Scorers = map[string]Scorer{
+	"standard": UnmarkedTimesLast{},
+	"marked":   MarkedSum{},
+	"calls":    CallsToWin{},
+	"pattern": PatternBonus{
+		Base:     UnmarkedTimesLast{},
+		Row:      100,
+		Column:   100,
+		Diagonal: 250,
+		Corners:  50,
+	},
+}
+
+// ScorerNames lists the built-in rules in a stable order
+func ScorerNames() []string {
+	names := make([]string, 0, len(Scorers))
+	for name := range Scorers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HasWon reports whether the card wins at some point in the call
+// sequence
+func (c Card) HasWon() bool {
+	return c.WinTime != never
+}
+
+// IsMarked reports whether n has been called at or before time t
+func (c Card) IsMarked(n, t int) bool {
+	called, ok := c.caller.TimesByNum[n]
+	return ok && called <= t
+}
+
+func (c Card) unmarkedSum(t int) int {
+	sum := 0
+	for _, r := range c.Grid {
+		for _, n := range r {
+			if !c.IsMarked(n, t) {
+				sum += n
+			}
+		}
+	}
+	return sum
+}
+
+func (c Card) markedSum(t int) int {
+	sum := 0
+	for _, r := range c.Grid {
+		for _, n := range r {
+			if c.IsMarked(n, t) {
+				sum += n
+			}
+		}
+	}
+	return sum
+}
+
+type patterns struct {
+	rows, cols, diags, corners int
+}
+
+// patternsAt counts the patterns that are fully marked at time t.
+// Rows and columns come straight from the precomputed win times;
+// diagonals and corners are cheap enough to check directly.
+func (c Card) patternsAt(t int) patterns {
+	var p patterns
+	for _, wt := range c.winTimeForRow {
+		if wt <= t {
+			p.rows++
+		}
+	}
+	for _, wt := range c.winTimeForCol {
+		if wt <= t {
+			p.cols++
+		}
+	}
+	size := len(c.Grid)
+	if size == 0 {
+		return p
+	}
+	down, up := true, true
+	for i := 0; i < size; i++ {
+		down = down && c.IsMarked(c.Grid[i][i], t)
+		up = up && c.IsMarked(c.Grid[size-1-i][i], t)
+	}
+	if down {
+		p.diags++
+	}
+	if up {
+		p.diags++
+	}
+	last := size - 1
+	if c.IsMarked(c.Grid[0][0], t) && c.IsMarked(c.Grid[0][last], t) &&
+		c.IsMarked(c.Grid[last][0], t) && c.IsMarked(c.Grid[last][last], t) {
+		p.corners++
+	}
+	return p
+}
+
+// An Expr is a user-defined scoring rule, written as an integer
+// arithmetic expression over these variables, all taken at the moment
+// the card wins:
+//
+//	unmarked  sum of unmarked numbers
+//	marked    sum of marked numbers
+//	last      the number that completed the card
+//	calls     how many numbers had been called
+//	size      the width of the card
+//	rows, cols, diags, corners
+//	          how many of each pattern are complete
+//
+// The operators are + - * / % with the usual precedence, unary minus,
+// and parentheses. Division or modulo by zero evaluates to 0.
+type Expr struct {
+	Source string
+	root   exprNode
+}
+
+func ParseExpr(s string) (*Expr, error) {
+	p := &exprParser{src: s}
+	p.next()
+	root, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok != "" {
+		return nil, fmt.Errorf("unexpected %q at offset %d in %q", p.tok, p.tokPos, s)
+	}
+	return &Expr{Source: s, root: root}, nil
+}
+
+func (e *Expr) Score(c *Card) int {
+	if !c.HasWon() {
+		return 0
+	}
+	pats := c.patternsAt(c.WinTime)
+	vars := map[string]int{
+		"unmarked": c.unmarkedSum(c.WinTime),
+		"marked":   c.markedSum(c.WinTime),
+		"last":     c.caller.NumsByTime[c.WinTime],
+		"calls":    c.WinTime + 1,
+		"size":     len(c.Grid),
+		"rows":     pats.rows,
+		"cols":     pats.cols,
+		"diags":    pats.diags,
+		"corners":  pats.corners,
+	}
+	return e.root.eval(vars)
+}
+
+var exprVars = map[string]bool{
+	"unmarked": true, "marked": true, "last": true, "calls": true,
+	"size": true, "rows": true, "cols": true, "diags": true, "corners": true,
+}
+
+type exprNode interface {
+	eval(vars map[string]int) int
+}
+
+type exprNum int
+
+func (n exprNum) eval(map[string]int) int { return int(n) }
+
+type exprVar string
+
+func (v exprVar) eval(vars map[string]int) int { return vars[string(v)] }
+
+type exprNeg struct{ x exprNode }
+
+func (n exprNeg) eval(vars map[string]int) int { return -n.x.eval(vars) }
+
+type exprBinary struct {
+	op   byte
+	l, r exprNode
+}
+
+func (b exprBinary) eval(vars map[string]int) int {
+	l, r := b.l.eval(vars), b.r.eval(vars)
+	switch b.op {
+	case '+':
+		return l + r
+	case '-':
+		return l - r
+	case '*':
+		return l * r
+	case '/':
+		if r == 0 {
+			return 0
+		}
+		return l / r
+	default:
+		if r == 0 {
+			return 0
+		}
+		return l % r
+	}
+}
+
+// exprParser is a small recursive-descent parser; the tokenizer keeps
+// one token of lookahead in tok
+type exprParser struct {
+	src    string
+	pos    int
+	tok    string
+	tokPos int
+}
+
+func (p *exprParser) next() {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+	p.tokPos = p.pos
+	if p.pos >= len(p.src) {
+		p.tok = ""
+		return
+	}
+	start := p.pos
+	r := rune(p.src[p.pos])
+	switch {
+	case unicode.IsDigit(r):
+		for p.pos < len(p.src) && unicode.IsDigit(rune(p.src[p.pos])) {
+			p.pos++
+		}
+	case unicode.IsLetter(r):
+		for p.pos < len(p.src) && unicode.IsLetter(rune(p.src[p.pos])) {
+			p.pos++
+		}
+	default:
+		p.pos++
+	}
+	p.tok = p.src[start:p.pos]
+}
+
+func (p *exprParser) parseSum() (exprNode, error) {
+	l, err := p.parseProduct()
+	for err == nil && (p.tok == "+" || p.tok == "-") {
+		op := p.tok[0]
+		p.next()
+		var r exprNode
+		if r, err = p.parseProduct(); err == nil {
+			l = exprBinary{op, l, r}
+		}
+	}
+	return l, err
+}
+
+func (p *exprParser) parseProduct() (exprNode, error) {
+	l, err := p.parseUnary()
+	for err == nil && (p.tok == "*" || p.tok == "/" || p.tok == "%") {
+		op := p.tok[0]
+		p.next()
+		var r exprNode
+		if r, err = p.parseUnary(); err == nil {
+			l = exprBinary{op, l, r}
+		}
+	}
+	return l, err
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.tok == "-" {
+		p.next()
+		x, err := p.parseUnary()
+		return exprNeg{x}, err
+	}
+	return p.parseAtom()
+}
+
+func (p *exprParser) parseAtom() (exprNode, error) {
+	tok, at := p.tok, p.tokPos
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("unexpected end of expression %q", p.src)
+	case tok == "(":
+		p.next()
+		x, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok != ")" {
+			return nil, fmt.Errorf("missing ) at offset %d in %q", p.tokPos, p.src)
+		}
+		p.next()
+		return x, nil
+	case unicode.IsDigit(rune(tok[0])):
+		n, err := strconv.Atoi(tok)
+		if err != nil {
+			return nil, err
+		}
+		p.next()
+		return exprNum(n), nil
+	case exprVars[tok]:
+		p.next()
+		return exprVar(tok), nil
+	}
+	return nil, fmt.Errorf("unknown %q at offset %d in %q (variables are %s)",
+		tok, at, p.src, strings.Join(exprVarNames(), ", "))
+}
+
+func exprVarNames() []string {
+	names := make([]string, 0, len(exprVars))
+	for name := range exprVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
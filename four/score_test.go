@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseExpr(t *testing.T) {
+	_, players := readTestInput(t)
+	// wins on its 5th call, 11, with 30..49 unmarked and the top row
+	// marked: 7 4 9 5 11
+	card := players.Cards[3]
+	for _, c := range []struct {
+		src  string
+		want int
+	}{
+		{"2+3*4", 14},
+		{"(2+3)*4", 20},
+		{"10-2-3", 5},
+		{"20/2/5", 2},
+		{"7%4*2", 6},
+		{"-2*3", -6},
+		{"--3", 3},
+		{"2*-3+1", -5},
+		{"-(1+2)", -3},
+		{"7/0", 0},
+		{"7%0", 0},
+		{"last*calls", 55},
+		{"marked + size", 36 + 5},
+		{"rows*100 + cols*10 + diags", 100},
+		{"unmarked*last", 8690},
+	} {
+		e, err := ParseExpr(c.src)
+		if err != nil {
+			t.Errorf("%q: %v", c.src, err)
+			continue
+		}
+		if got := e.Score(card); got != c.want {
+			t.Errorf("%q scores %d, want %d", c.src, got, c.want)
+		}
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	for _, c := range []struct {
+		src, want string
+	}{
+		{"unmarked*bonus", `unknown "bonus"`},
+		{"2+", "unexpected end"},
+		{"(2+3", "missing )"},
+		{"2 3", `unexpected "3"`},
+		{"2^3", `unexpected "^"`},
+	} {
+		_, err := ParseExpr(c.src)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%q: got error %v, want one containing %q", c.src, err, c.want)
+		}
+	}
+}
+
+func TestExprNeverWon(t *testing.T) {
+	_, players := readTestInput(t)
+	e, err := ParseExpr("1 + calls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := e.Score(players.Cards[4]); got != 0 {
+		t.Errorf("a card that never wins scores %d, want 0", got)
+	}
+}
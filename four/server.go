@@ -0,0 +1,340 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Game is a live bingo game served over HTTP. The Caller knows the
+// whole call sequence up front, so every registered Card already knows
+// its WinTime; the game just reveals the sequence one number at a
+// time and checks "bingo!" claims against it.
+//
+//	POST /cards          body is the card rows, returns {"card": id}
+//	POST /call           calls the next number
+//	POST /bingo?card=id  claims a win, 200 if valid and 409 if not
+//	GET  /events         WebSocket stream of Events; add ?card=id to
+//	                     also receive the marks on that card
+type Game struct {
+	caller *Caller
+	scorer Scorer
+
+	mu    sync.Mutex
+	now   int // time of the latest call, -1 before the first
+	cards []*Card
+	won   map[int]bool
+	subs  map[*subscriber]bool
+}
+
+// An Event is what a client receives over its WebSocket
+type Event struct {
+	Type   string `json:"type"` // call, mark, win or over
+	Time   int    `json:"time"`
+	Number int    `json:"number"`
+	Card   int    `json:"card,omitempty"`
+	Row    int    `json:"row"`
+	Col    int    `json:"col"`
+	Score  int    `json:"score,omitempty"`
+}
+
+type subscriber struct {
+	card   int // 0 means "no card, just the calls and wins"
+	events chan Event
+}
+
+func NewGame(caller *Caller, scorer Scorer) *Game {
+	return &Game{
+		caller: caller,
+		scorer: scorer,
+		now:    -1,
+		won:    make(map[int]bool),
+		subs:   make(map[*subscriber]bool),
+	}
+}
+
+// Register adds a card from its rows of text and returns its id.
+// Ids start at 1, the same way the CLI numbers cards.
+func (g *Game) Register(rows []string) (int, error) {
+	if len(rows) == 0 {
+		return 0, fmt.Errorf("a card needs at least one row")
+	}
+	card, err := NewCard(g.caller, rows[0])
+	if err != nil {
+		return 0, err
+	}
+	for _, row := range rows[1:] {
+		if card.IsComplete() {
+			return 0, fmt.Errorf("card has more than %d rows", len(card.Grid))
+		}
+		// cards come off the network, and AddRow trusts its rows to
+		// be as wide as the card
+		if n := len(strings.Fields(row)); n != len(card.Grid) {
+			return 0, fmt.Errorf("card row %q has %d numbers, want %d", row, n, len(card.Grid))
+		}
+		if err := card.AddRow(row); err != nil {
+			return 0, err
+		}
+	}
+	if !card.IsComplete() {
+		return 0, fmt.Errorf("card has %d rows, want %d", len(rows), len(card.Grid))
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.cards = append(g.cards, card)
+	return len(g.cards), nil
+}
+
+// Call reveals the next number and reports it, and every mark it
+// makes, to the subscribers. ok is false once the sequence runs out.
+func (g *Game) Call() (n int, ok bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.now+1 >= len(g.caller.Sequence) {
+		g.broadcast(Event{Type: "over", Time: g.now})
+		return 0, false
+	}
+	g.now++
+	n = g.caller.NumsByTime[g.now]
+	g.broadcast(Event{Type: "call", Time: g.now, Number: n})
+	for id, c := range g.cards {
+		for i, r := range c.Grid {
+			for j, m := range r {
+				if m == n {
+					g.broadcast(Event{Type: "mark", Time: g.now, Number: n,
+						Card: id + 1, Row: i, Col: j})
+				}
+			}
+		}
+	}
+	return n, true
+}
+
+// Claim checks a "bingo!" for card id. A claim is valid once the
+// numbers called so far reach the card's WinTime; the first valid
+// claim for a card is announced to everyone.
+func (g *Game) Claim(id int) (valid bool, score int, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if id < 1 || id > len(g.cards) {
+		return false, 0, fmt.Errorf("no card %d", id)
+	}
+	c := g.cards[id-1]
+	if !c.HasWon() || g.now < c.WinTime {
+		return false, 0, nil
+	}
+	score = g.scorer.Score(c)
+	if !g.won[id] {
+		g.won[id] = true
+		g.broadcast(Event{Type: "win", Time: g.now, Card: id, Score: score,
+			Number: g.caller.NumsByTime[c.WinTime]})
+	}
+	return true, score, nil
+}
+
+// Run calls a number every interval until the sequence runs out or
+// stop is closed
+func (g *Game) Run(interval time.Duration, stop <-chan struct{}) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-tick.C:
+			if _, ok := g.Call(); !ok {
+				return
+			}
+		}
+	}
+}
+
+// broadcast must be called with g.mu held. A subscriber that has
+// fallen a whole buffer behind is dropped rather than stalling the game.
+func (g *Game) broadcast(e Event) {
+	for s := range g.subs {
+		if e.Type == "mark" && e.Card != s.card {
+			continue
+		}
+		select {
+		case s.events <- e:
+		default:
+			delete(g.subs, s)
+			close(s.events)
+		}
+	}
+}
+
+func (g *Game) subscribe(card int) *subscriber {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	s := &subscriber{card: card, events: make(chan Event, 256)}
+	g.subs[s] = true
+	return s
+}
+
+func (g *Game) unsubscribe(s *subscriber) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.subs[s] {
+		delete(g.subs, s)
+		close(s.events)
+	}
+}
+
+func (g *Game) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cards", g.handleCards)
+	mux.HandleFunc("/call", g.handleCall)
+	mux.HandleFunc("/bingo", g.handleBingo)
+	mux.HandleFunc("/events", g.handleEvents)
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (g *Game) handleCards(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST a card", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<16))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var rows []string
+	for _, row := range strings.Split(string(body), "\n") {
+		if strings.TrimSpace(row) != "" {
+			rows = append(rows, row)
+		}
+	}
+	id, err := g.Register(rows)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]int{"card": id})
+}
+
+func (g *Game) handleCall(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST to call a number", http.StatusMethodNotAllowed)
+		return
+	}
+	n, ok := g.Call()
+	if !ok {
+		http.Error(w, "every number has been called", http.StatusGone)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"number": n})
+}
+
+func (g *Game) handleBingo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST to claim a win", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(r.URL.Query().Get("card"))
+	if err != nil {
+		http.Error(w, "card must be a number", http.StatusBadRequest)
+		return
+	}
+	valid, score, err := g.Claim(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	status := http.StatusOK
+	if !valid {
+		status = http.StatusConflict
+	}
+	writeJSON(w, status, map[string]interface{}{"valid": valid, "score": score})
+}
+
+func (g *Game) handleEvents(w http.ResponseWriter, r *http.Request) {
+	card := 0
+	if s := r.URL.Query().Get("card"); s != "" {
+		var err error
+		if card, err = strconv.Atoi(s); err != nil {
+			http.Error(w, "card must be a number", http.StatusBadRequest)
+			return
+		}
+	}
+	// subscribe before the handshake completes, so a client that calls
+	// a number as soon as it is connected still hears about it
+	s := g.subscribe(card)
+	defer g.unsubscribe(s)
+	ws, err := wsUpgrade(w, r)
+	if err != nil {
+		return
+	}
+	defer ws.Close()
+
+	// the client never sends us anything we care about, but reading is
+	// how we notice that it went away
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case <-gone:
+			return
+		case e, ok := <-s.events:
+			if !ok {
+				return
+			}
+			msg, _ := json.Marshal(e)
+			if err := ws.WriteText(msg); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// ListenLocal only accepts loopback addresses: the game has no
+// authentication, so it should never be reachable from the network
+func ListenLocal(addr string) (net.Listener, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if host != "localhost" {
+		ip := net.ParseIP(host)
+		if ip == nil || !ip.IsLoopback() {
+			return nil, fmt.Errorf("refusing to serve on %q, use a loopback address", addr)
+		}
+	}
+	return net.Listen("tcp", addr)
+}
+
+// Serve plays the game on addr until the process is stopped. With a
+// zero interval numbers are only called on POST /call.
+func (g *Game) Serve(addr string, interval time.Duration) error {
+	l, err := ListenLocal(addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("bingo server listening on http://%s", l.Addr())
+	if interval > 0 {
+		go g.Run(interval, nil)
+	}
+	return http.Serve(l, g.Handler())
+}
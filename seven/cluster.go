@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// With k meeting points instead of one, every crab goes to whichever
+// point is cheapest for it. For the costs here that is just the nearest
+// point, so in sorted order the crabs at each point form one unbroken
+// run, and the best k runs can be found exactly by dynamic programming
+// over where each run ends.
+
+// runs gives the fuel for a run of sorted crabs to meet at a point in
+// O(log n), using prefix sums of the weights, the positions and their
+// squares
+type runs struct {
+	crabs             []Crab
+	count, sum, sumSq []int // over crabs[:i]
+	cost              FuelCost
+}
+
+func newRuns(crabs []Crab, cost FuelCost) *runs {
+	r := &runs{
+		crabs: crabs,
+		count: make([]int, len(crabs)+1),
+		sum:   make([]int, len(crabs)+1),
+		sumSq: make([]int, len(crabs)+1),
+		cost:  cost,
+	}
+	for i, c := range crabs {
+		r.count[i+1] = r.count[i] + c.Weight
+		r.sum[i+1] = r.sum[i] + c.Weight*c.Pos
+		r.sumSq[i+1] = r.sumSq[i] + c.Weight*c.Pos*c.Pos
+	}
+	return r
+}
+
+// fuel is the cost for crabs[a:b] to meet at pos
+func (r *runs) fuel(a, b, pos int) int {
+	m := a + sort.Search(b-a, func(i int) bool { return r.crabs[a+i].Pos >= pos })
+	cl, sl, ql := r.count[m]-r.count[a], r.sum[m]-r.sum[a], r.sumSq[m]-r.sumSq[a]
+	cr, sr, qr := r.count[b]-r.count[m], r.sum[b]-r.sum[m], r.sumSq[b]-r.sumSq[m]
+	d := pos*cl - sl + sr - pos*cr
+	d2 := pos*pos*(cl+cr) - 2*pos*(sl+sr) + ql + qr
+	switch r.cost.(type) {
+	case Triangular:
+		return (d2 + d) / 2
+	case Quadratic:
+		return d2
+	}
+	return d
+}
+
+// best is the cheapest point for crabs[a:b]: the median for linear
+// costs, and next to the mean for the others
+func (r *runs) best(a, b int) (pos, fuel int) {
+	n := r.count[b] - r.count[a]
+	if _, ok := r.cost.(Linear); ok {
+		// the lower median, counting a weighted crab as that many crabs
+		m := a + sort.Search(b-a, func(i int) bool { return r.count[a+i+1]-r.count[a] > (n-1)/2 })
+		pos = r.crabs[m].Pos
+		return pos, r.fuel(a, b, pos)
+	}
+	mean := (r.sum[b] - r.sum[a]) / n
+	pos, fuel = mean-1, r.fuel(a, b, mean-1)
+	for x := mean; x <= mean+2; x++ {
+		if f := r.fuel(a, b, x); f < fuel {
+			pos, fuel = x, f
+		}
+	}
+	return pos, fuel
+}
+
+// A Cluster is the crabs that meet at one point
+type Cluster struct {
+	Point int
+	Fuel  int
+	Crabs []Crab
+}
+
+// Clusters splits the sorted crabs among k meeting points with the
+// least total fuel. It takes O(k n²) time, which is fine for puzzle
+// input, and works for linear, triangular and quadratic costs.
+func Clusters(crabs []Crab, k int, cost FuelCost) ([]Cluster, error) {
+	switch cost.(type) {
+	case Linear, Triangular, Quadratic:
+	default:
+		return nil, fmt.Errorf("k meeting points need a linear, triangular or quadratic cost")
+	}
+	if k < 1 {
+		return nil, fmt.Errorf("need at least one meeting point, not %d", k)
+	}
+	// more points than distinct positions would only split up crabs
+	// that are already together
+	distinct := 0
+	for i := range crabs {
+		if i == 0 || crabs[i].Pos != crabs[i-1].Pos {
+			distinct++
+		}
+	}
+	if k > distinct {
+		k = distinct
+	}
+	n := len(crabs)
+	r := newRuns(crabs, cost)
+
+	// best[j][i] is the least fuel for crabs[:i] at j points, and
+	// start[j][i] is where the last of those runs starts
+	best := make([][]int, k+1)
+	start := make([][]int, k+1)
+	for j := range best {
+		best[j] = make([]int, n+1)
+		start[j] = make([]int, n+1)
+	}
+	for i := 1; i <= n; i++ {
+		_, best[1][i] = r.best(0, i)
+	}
+	for j := 2; j <= k; j++ {
+		for i := j; i <= n; i++ {
+			best[j][i] = -1
+			for m := j - 1; m < i; m++ {
+				_, f := r.best(m, i)
+				if f += best[j-1][m]; best[j][i] < 0 || f < best[j][i] {
+					best[j][i], start[j][i] = f, m
+				}
+			}
+		}
+	}
+
+	clusters := make([]Cluster, k)
+	for j, i := k, n; j >= 1; j-- {
+		m := start[j][i]
+		pos, fuel := r.best(m, i)
+		clusters[j-1] = Cluster{Point: pos, Fuel: fuel, Crabs: crabs[m:i]}
+		i = m
+	}
+	return clusters, nil
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Gathering is as many crabs as a fuel budget can bring to one point
+type Gathering struct {
+	Point, Crabs, Fuel int
+}
+
+// MaxGathered finds the point where a fuel budget gathers the most
+// crabs, using the least fuel and then the leftmost point to break
+// ties. Fuel never decreases with distance, so at any one point the
+// budget is best spent on the nearest crabs, and those are found by
+// walking outwards from the point through the sorted crabs, taking as
+// many of each weighted crab as the budget still allows.
+func MaxGathered(crabs []Crab, cost FuelCost, budget int) Gathering {
+	var best Gathering
+	if len(crabs) == 0 || budget < 0 {
+		return best
+	}
+	best.Point = crabs[0].Pos
+	for p := crabs[0].Pos; p <= crabs[len(crabs)-1].Pos; p++ {
+		g := Gathering{Point: p}
+		right := searchCrabs(crabs, p)
+		left := right - 1
+		for left >= 0 || right < len(crabs) {
+			var c Crab
+			var f int
+			if right >= len(crabs) || left >= 0 && p-crabs[left].Pos <= crabs[right].Pos-p {
+				c = crabs[left]
+				f = cost.Fuel(p - c.Pos)
+				left--
+			} else {
+				c = crabs[right]
+				f = cost.Fuel(c.Pos - p)
+				right++
+			}
+			n := c.Weight
+			if f > 0 && (budget-g.Fuel)/f < n {
+				n = (budget - g.Fuel) / f
+			}
+			g.Crabs += n
+			g.Fuel += n * f
+			if n < c.Weight {
+				break
+			}
+		}
+		if g.Crabs > best.Crabs || g.Crabs == best.Crabs && g.Fuel < best.Fuel {
+			best = g
+		}
+	}
+	return best
+}
+
+// searchCrabs is sort.SearchInts for sorted crabs: the index of the
+// first crab at or right of pos
+func searchCrabs(crabs []Crab, pos int) int {
+	return sort.Search(len(crabs), func(i int) bool { return crabs[i].Pos >= pos })
+}
+
+// A Span is the positions from Lo to Hi, inclusive
+type Span struct {
+	Lo, Hi int
+}
+
+func (s Span) String() string {
+	return fmt.Sprintf("%d..%d", s.Lo, s.Hi)
+}
+
+// Constraints limit where the crabs can meet. A constraints file has
+// one rule a line:
+//
+//	range 16 10..20   # crabs at 16 can only get to 10 through 20
+//	reach 14 3        # crabs at 14 can move at most 3
+//	forbid 5          # nobody can meet at 5
+//	forbid 7..9       # or anywhere from 7 through 9
+//
+// Ranges for positions with no crabs at them are ignored.
+type Constraints struct {
+	Ranges    map[int]Span
+	Forbidden []Span // sorted and merged
+}
+
+func parseSpan(s string) (Span, error) {
+	f := strings.SplitN(s, "..", 2)
+	lo, err := strconv.Atoi(f[0])
+	if err != nil {
+		return Span{}, fmt.Errorf("%q is not a position or a range lo..hi", s)
+	}
+	hi := lo
+	if len(f) == 2 {
+		if hi, err = strconv.Atoi(f[1]); err != nil {
+			return Span{}, fmt.Errorf("%q is not a position or a range lo..hi", s)
+		}
+	}
+	if lo > hi {
+		return Span{}, fmt.Errorf("range %q runs backwards", s)
+	}
+	return Span{lo, hi}, nil
+}
+
+// ParseConstraints reads a constraints file
+func ParseConstraints(r io.Reader) (*Constraints, error) {
+	c := &Constraints{Ranges: make(map[int]Span)}
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if err := c.parseLine(strings.Fields(line)); err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(c.Forbidden, func(i, j int) bool {
+		return c.Forbidden[i].Lo < c.Forbidden[j].Lo
+	})
+	var merged []Span
+	for _, s := range c.Forbidden {
+		if n := len(merged); n > 0 && s.Lo <= merged[n-1].Hi+1 {
+			if s.Hi > merged[n-1].Hi {
+				merged[n-1].Hi = s.Hi
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+	c.Forbidden = merged
+	return c, nil
+}
+
+func (c *Constraints) parseLine(f []string) error {
+	if len(f) == 0 {
+		return nil
+	}
+	switch f[0] {
+	case "range", "reach":
+		if len(f) != 3 {
+			return fmt.Errorf("expected: range POS LO..HI, or reach POS DISTANCE")
+		}
+		pos, err := strconv.Atoi(f[1])
+		if err != nil {
+			return fmt.Errorf("%q is not a position", f[1])
+		}
+		var s Span
+		if f[0] == "range" {
+			if s, err = parseSpan(f[2]); err != nil {
+				return err
+			}
+		} else {
+			d, err := strconv.Atoi(f[2])
+			if err != nil || d < 0 {
+				return fmt.Errorf("%q is not a distance", f[2])
+			}
+			s = Span{pos - d, pos + d}
+		}
+		// two rules for the same crabs both apply
+		if old, ok := c.Ranges[pos]; ok {
+			if old.Lo > s.Lo {
+				s.Lo = old.Lo
+			}
+			if old.Hi < s.Hi {
+				s.Hi = old.Hi
+			}
+		}
+		c.Ranges[pos] = s
+	case "forbid":
+		if len(f) != 2 {
+			return fmt.Errorf("expected: forbid POS or forbid LO..HI")
+		}
+		s, err := parseSpan(f[1])
+		if err != nil {
+			return err
+		}
+		c.Forbidden = append(c.Forbidden, s)
+	default:
+		return fmt.Errorf("unknown rule %q", f[0])
+	}
+	return nil
+}
+
+// reachable is where every crab can get to
+func (c *Constraints) reachable(crabs []Crab) Span {
+	s := Span{math.MinInt, math.MaxInt}
+	for pos, r := range c.Ranges {
+		if i := searchCrabs(crabs, pos); i == len(crabs) || crabs[i].Pos != pos {
+			continue
+		}
+		if r.Lo > s.Lo {
+			s.Lo = r.Lo
+		}
+		if r.Hi < s.Hi {
+			s.Hi = r.Hi
+		}
+	}
+	return s
+}
+
+// allowedAtOrBelow is the largest position no greater than x that
+// isn't forbidden
+func (c *Constraints) allowedAtOrBelow(x int) int {
+	for i := len(c.Forbidden) - 1; i >= 0; i-- {
+		if f := c.Forbidden[i]; f.Lo <= x && x <= f.Hi {
+			x = f.Lo - 1
+		}
+	}
+	return x
+}
+
+func (c *Constraints) allowedAtOrAbove(x int) int {
+	for _, f := range c.Forbidden {
+		if f.Lo <= x && x <= f.Hi {
+			x = f.Hi + 1
+		}
+	}
+	return x
+}
+
+// ConstrainedOptimum finds the cheapest meeting point that every crab
+// can reach and that isn't forbidden, the leftmost on ties. ok is false
+// if there is no such point. crabs must be sorted.
+//
+// A convex total fuel only rises moving away from its unconstrained
+// minimum, so the answer is whichever allowed point is nearest to it on
+// either side. Otherwise every allowed point between the outermost
+// crabs is tried, along with the nearest allowed ones beyond them.
+func ConstrainedOptimum(crabs []Crab, cost FuelCost, c *Constraints) (pos, fuel int, ok bool) {
+	if len(crabs) == 0 {
+		return 0, 0, false
+	}
+	within := c.reachable(crabs)
+	if within.Lo > within.Hi {
+		return 0, 0, false
+	}
+	var candidates []int
+	consider := func(x int) {
+		if within.Lo <= x && x <= within.Hi {
+			candidates = append(candidates, x)
+		}
+	}
+	clamp := func(x int) int {
+		if x < within.Lo {
+			return within.Lo
+		}
+		if x > within.Hi {
+			return within.Hi
+		}
+		return x
+	}
+	if cost.Convex() {
+		best, _ := Optimize(crabs, cost)
+		best = clamp(best)
+		consider(c.allowedAtOrBelow(best))
+		consider(c.allowedAtOrAbove(best))
+	} else {
+		lo, hi := clamp(crabs[0].Pos), clamp(crabs[len(crabs)-1].Pos)
+		consider(c.allowedAtOrBelow(lo))
+		for x := lo; x <= hi; x++ {
+			if c.allowedAtOrAbove(x) == x {
+				consider(x)
+			}
+		}
+		consider(c.allowedAtOrAbove(hi))
+	}
+	for _, x := range candidates {
+		f := TotalFuel(x, crabs, cost)
+		if !ok || f < fuel || f == fuel && x < pos {
+			pos, fuel, ok = x, f, true
+		}
+	}
+	return pos, fuel, ok
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A FuelCost is how much fuel a crab burns to move a distance. Every
+// cost here is zero at distance 0 and never decreases with distance, so
+// the best meeting point always lies between the outermost crabs.
+type FuelCost interface {
+	Fuel(distance int) int
+	// Convex reports whether each extra step costs at least as much as
+	// the one before. Then the total fuel, a sum of convex functions of
+	// the meeting point, is convex too, and has no false minima.
+	Convex() bool
+}
+
+// Linear is part one: one unit of fuel per step
+type Linear struct{}
+
+func (Linear) Fuel(d int) int { return d }
+func (Linear) Convex() bool   { return true }
+
+// Triangular is part two: the nth step costs n, so the Gauss formula
+type Triangular struct{}
+
+func (Triangular) Fuel(d int) int { return d * (d + 1) / 2 }
+func (Triangular) Convex() bool   { return true }
+
+type Quadratic struct{}
+
+func (Quadratic) Fuel(d int) int { return d * d }
+func (Quadratic) Convex() bool   { return true }
+
+// Capped stops charging once Cost reaches Max. The cap bends the cost
+// curve the wrong way, so it isn't convex.
+type Capped struct {
+	Cost FuelCost
+	Max  int
+}
+
+func (c Capped) Fuel(d int) int {
+	if f := c.Cost.Fuel(d); f < c.Max {
+		return f
+	}
+	return c.Max
+}
+
+func (c Capped) Convex() bool { return false }
+
+// A Table is a user-supplied cost: Table[d] is the fuel for distance d,
+// and past the end each step costs the same as the last one listed
+type Table []int
+
+func (t Table) Fuel(d int) int {
+	if d < len(t) {
+		return t[d]
+	}
+	last := len(t) - 1
+	step := 0
+	if last > 0 {
+		step = t[last] - t[last-1]
+	}
+	return t[last] + step*(d-last)
+}
+
+func (t Table) Convex() bool {
+	for d := 2; d < len(t); d++ {
+		if t[d]-t[d-1] < t[d-1]-t[d-2] {
+			return false
+		}
+	}
+	return true
+}
+
+// CostFunc adapts a Go function to a FuelCost
+type CostFunc struct {
+	F        func(distance int) int
+	IsConvex bool
+}
+
+func (c CostFunc) Fuel(d int) int { return c.F(d) }
+func (c CostFunc) Convex() bool   { return c.IsConvex }
+
+var FuelCosts = map[string]FuelCost{
+	"linear":     Linear{},
+	"triangular": Triangular{},
+	"quadratic":  Quadratic{},
+}
+
+// ParseFuelCost understands the names in FuelCosts, "capped:MAX:COST",
+// and "table:F0,F1,F2,..." for a Table
+func ParseFuelCost(s string) (FuelCost, error) {
+	if cost, ok := FuelCosts[s]; ok {
+		return cost, nil
+	}
+	parts := strings.SplitN(s, ":", 2)
+	switch parts[0] {
+	case "capped":
+		f := strings.SplitN(s, ":", 3)
+		if len(f) != 3 {
+			return nil, fmt.Errorf("expected capped:MAX:COST, not %q", s)
+		}
+		max, err := strconv.Atoi(f[1])
+		if err != nil || max < 0 {
+			return nil, fmt.Errorf("expected capped:MAX:COST, not %q", s)
+		}
+		cost, err := ParseFuelCost(f[2])
+		if err != nil {
+			return nil, err
+		}
+		return Capped{Cost: cost, Max: max}, nil
+	case "table":
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("expected table:F0,F1,..., not %q", s)
+		}
+		var t Table
+		for _, f := range strings.Split(parts[1], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(f))
+			if err != nil {
+				return nil, fmt.Errorf("%q is not an integer", f)
+			}
+			if len(t) == 0 && n != 0 || len(t) > 0 && n < t[len(t)-1] {
+				return nil, fmt.Errorf("a fuel table must start at 0 and never decrease, not %q", parts[1])
+			}
+			t = append(t, n)
+		}
+		return t, nil
+	}
+	names := make([]string, 0, len(FuelCosts))
+	for name := range FuelCosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown fuel cost %q; try %s, capped:MAX:COST or table:F0,F1,...",
+		s, strings.Join(names, ", "))
+}
+
+// TotalFuel is the fuel for every crab to meet at pos
+func TotalFuel(pos int, crabs []Crab, cost FuelCost) int {
+	total := 0
+	for _, c := range crabs {
+		if c.Pos < pos {
+			total += c.Weight * cost.Fuel(pos-c.Pos)
+		} else {
+			total += c.Weight * cost.Fuel(c.Pos-pos)
+		}
+	}
+	return total
+}
+
+// Optimize finds the meeting point with the least total fuel, and when
+// several tie, the leftmost of them. crabs must be sorted.
+//
+// For a convex cost the total fuel only ever falls and then rises, so a
+// binary search for the first position where the next step stops
+// getting cheaper finds the minimum with O(log range) evaluations.
+// Anything else gets every position from the leftmost crab to the
+// rightmost tried.
+func Optimize(crabs []Crab, cost FuelCost) (pos, fuel int) {
+	if len(crabs) == 0 {
+		return 0, 0
+	}
+	lo, hi := crabs[0].Pos, crabs[len(crabs)-1].Pos
+	if !cost.Convex() {
+		pos, fuel = lo, TotalFuel(lo, crabs, cost)
+		for x := lo + 1; x <= hi; x++ {
+			if f := TotalFuel(x, crabs, cost); f < fuel {
+				pos, fuel = x, f
+			}
+		}
+		return pos, fuel
+	}
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if TotalFuel(mid+1, crabs, cost) >= TotalFuel(mid, crabs, cost) {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return lo, TotalFuel(lo, crabs, cost)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Crab is Weight crabs at the same position, so a heavy crab costs no
+// more to store than a light one
+type Crab struct {
+	Pos, Weight int
+}
+
+// String writes the crab the way ReadCrabs reads it
+func (c Crab) String() string {
+	if c.Weight == 1 {
+		return strconv.Itoa(c.Pos)
+	}
+	return fmt.Sprintf("%d:%d", c.Pos, c.Weight)
+}
+
+// SortCrabs sorts the crabs by position, merging crabs at the same
+// position into one
+func SortCrabs(crabs []Crab) []Crab {
+	sort.Slice(crabs, func(i, j int) bool { return crabs[i].Pos < crabs[j].Pos })
+	merged := crabs[:0]
+	for _, c := range crabs {
+		if n := len(merged); n > 0 && merged[n-1].Pos == c.Pos {
+			merged[n-1].Weight += c.Weight
+			continue
+		}
+		merged = append(merged, c)
+	}
+	return merged
+}
+
+// totalWeight is how many crabs there are in all
+func totalWeight(crabs []Crab) int {
+	n := 0
+	for _, c := range crabs {
+		n += c.Weight
+	}
+	return n
+}
+
+// addWeight keeps a running count of the crabs read, which has to fit
+// in an int for the fuel sums to make any sense
+func addWeight(total *int, weight, lineNo, column int) error {
+	if *total > math.MaxInt-weight {
+		return fmt.Errorf("line %d, column %d: the weights add up to more than %d crabs",
+			lineNo, column, math.MaxInt)
+	}
+	*total += weight
+	return nil
+}
+
+// A BadToken is a piece of input that isn't a crab, and where it was
+type BadToken struct {
+	Line, Column int
+	Token        string
+	Reason       string
+}
+
+func (b BadToken) String() string {
+	return fmt.Sprintf("line %d, column %d: %q %s", b.Line, b.Column, b.Token, b.Reason)
+}
+
+// BadInput lists every malformed token, not just the first
+type BadInput []BadToken
+
+func (b BadInput) Error() string {
+	lines := make([]string, len(b))
+	for i, t := range b {
+		lines[i] = t.String()
+	}
+	return fmt.Sprintf("%d malformed crabs:\n\t%s", len(b), strings.Join(lines, "\n\t"))
+}
+
+// scanTokens calls each with every token in r, where tokens are split
+// by newlines and the bytes isSep picks out
+func scanTokens(r io.Reader, isSep func(byte) bool, each func(lineNo, column int, token string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		for i := 0; i < len(line); {
+			if isSep(line[i]) {
+				i++
+				continue
+			}
+			start := i
+			for i < len(line) && !isSep(line[i]) {
+				i++
+			}
+			if err := each(lineNo, start+1, line[start:i]); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r'
+}
+
+// ReadCrabs reads crab positions from any number of lines, separated by
+// commas, spaces or newlines. A crab written pos:weight counts as
+// weight crabs at pos. The crabs are returned unsorted.
+func ReadCrabs(r io.Reader) ([]Crab, error) {
+	var crabs []Crab
+	var bad BadInput
+	total := 0
+	isSep := func(c byte) bool { return c == ',' || isSpace(c) }
+	err := scanTokens(r, isSep, func(lineNo, column int, token string) error {
+		pos, weight, reason := parseCrab(token)
+		if reason != "" {
+			bad = append(bad, BadToken{lineNo, column, token, reason})
+			return nil
+		}
+		if err := addWeight(&total, weight, lineNo, column); err != nil {
+			return err
+		}
+		crabs = append(crabs, Crab{pos, weight})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(bad) > 0 {
+		return nil, bad
+	}
+	if len(crabs) == 0 {
+		return nil, fmt.Errorf("no crabs in the input")
+	}
+	return crabs, nil
+}
+
+// A Crab2D is Weight crabs at the same point on a plane
+type Crab2D struct {
+	Point
+	Weight int
+}
+
+// ReadCrabs2D is ReadCrabs for crabs on a plane, written x,y or
+// x,y:weight and separated by semicolons, spaces or newlines
+func ReadCrabs2D(r io.Reader) ([]Crab2D, error) {
+	var crabs []Crab2D
+	var bad BadInput
+	total := 0
+	isSep := func(c byte) bool { return c == ';' || isSpace(c) }
+	err := scanTokens(r, isSep, func(lineNo, column int, token string) error {
+		xy, weightStr := token, ""
+		if i := strings.Index(token, ":"); i >= 0 {
+			xy, weightStr = token[:i], token[i:]
+		}
+		f := strings.Split(xy, ",")
+		if len(f) != 2 {
+			bad = append(bad, BadToken{lineNo, column, token, "is not an x,y position"})
+			return nil
+		}
+		x, _, reason := parseCrab(f[0])
+		y, weight, reason2 := parseCrab(f[1] + weightStr)
+		if reason == "" {
+			reason = reason2
+		}
+		if reason != "" {
+			bad = append(bad, BadToken{lineNo, column, token, reason})
+			return nil
+		}
+		if err := addWeight(&total, weight, lineNo, column); err != nil {
+			return err
+		}
+		crabs = append(crabs, Crab2D{Point{x, y}, weight})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(bad) > 0 {
+		return nil, bad
+	}
+	if len(crabs) == 0 {
+		return nil, fmt.Errorf("no crabs in the input")
+	}
+	return crabs, nil
+}
+
+// parseCrab reads pos or pos:weight, or explains what's wrong with it
+func parseCrab(token string) (pos, weight int, reason string) {
+	posStr, weightStr := token, "1"
+	if i := strings.Index(token, ":"); i >= 0 {
+		posStr, weightStr = token[:i], token[i+1:]
+	}
+	pos, err := strconv.Atoi(posStr)
+	if err != nil {
+		return 0, 0, "is not a position"
+	}
+	weight, err = strconv.Atoi(weightStr)
+	if err != nil || weight < 1 {
+		return 0, 0, "does not have a positive whole weight"
+	}
+	return pos, weight, ""
+}
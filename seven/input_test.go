@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestReadCrabsWeights(t *testing.T) {
+	crabs, err := ReadCrabs(strings.NewReader("3:2,1\n5:2000000,3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(crabs), "[3:2 1 5:2000000 3]"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := fmt.Sprint(SortCrabs(crabs)), "[1 3:3 5:2000000]"; got != want {
+		t.Errorf("sorted, got %v, want %v", got, want)
+	}
+	in := fmt.Sprintf("1:%d,2:1", math.MaxInt)
+	if _, err := ReadCrabs(strings.NewReader(in)); err == nil {
+		t.Error("weights adding up to more than an int should be an error")
+	}
+}
+
+func TestReadCrabs2DWeights(t *testing.T) {
+	crabs, err := ReadCrabs2D(strings.NewReader("1,2:3; -4,5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Crab2D{{Point{1, 2}, 3}, {Point{-4, 5}, 1}}
+	if fmt.Sprint(crabs) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", crabs, want)
+	}
+}
@@ -1,31 +1,98 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
+	"io"
 	"math"
 	"os"
-	"sort"
-	"strconv"
-	"strings"
 	"time"
 )
 
 func main() {
-	var inCrabs []int
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		numStrings := strings.Split(scanner.Text(), `,`)
-		if inCrabs == nil {
-			inCrabs = make([]int, len(numStrings))
+	costName := flag.String("cost", "",
+		"also find the exact optimum for this fuel cost: linear, triangular, quadratic,\n"+
+			"capped:MAX:COST, or table:F0,F1,... giving the fuel for each distance")
+	verify := flag.Bool("verify", false,
+		"check the median and mean shortcuts against the fuel at every position")
+	meetings := flag.Int("k", 0,
+		"also split the crabs among this many meeting points, using -cost or else linear costs")
+	plane := flag.Bool("2d", false,
+		"the crabs are on a plane, written x,y and separated by semicolons, spaces or newlines")
+	metric := flag.String("metric", "manhattan",
+		"with -2d, how to measure distance: manhattan, chebyshev or euclidean")
+	stream := flag.Bool("stream", false,
+		"read crabs arriving (5 or +5) and leaving (-5), and keep the best linear meeting point")
+	curveFile := flag.String("curve", "",
+		"write the fuel at every position, using -cost or else linear costs, to this CSV file")
+	sparkline := flag.Int("sparkline", 0,
+		"draw the fuel at every position as a sparkline this many characters wide")
+	breakdown := flag.Bool("breakdown", false,
+		"list each crab's distance and fuel at the optimum, costliest first")
+	budget := flag.Int("budget", -1,
+		"find the most crabs this much fuel can gather at one point, using -cost or else linear costs")
+	constraintsFile := flag.String("constraints", "",
+		"find the cheapest meeting point allowed by the ranges and forbidden positions in this file")
+	flag.Parse()
+	var cost FuelCost
+	if *costName != "" {
+		var err error
+		if cost, err = ParseFuelCost(*costName); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
 		}
-		for i, n := range strings.Split(scanner.Text(), `,`) {
-			pos, err := strconv.Atoi(n)
-			if err != nil {
-				panic(err)
-			}
-			inCrabs[i] = pos
+	}
+	var constraints *Constraints
+	if *constraintsFile != "" {
+		f, err := os.Open(*constraintsFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		constraints, err = ParseConstraints(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *constraintsFile, err)
+			os.Exit(1)
+		}
+	}
+	// the other modes fall back to part one's costs
+	otherCost, otherName := cost, *costName
+	if otherCost == nil {
+		otherCost, otherName = Linear{}, "linear"
+	}
+
+	if *stream {
+		if err := RunStream(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *plane {
+		crabs, err := ReadCrabs2D(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		start := time.Now()
+		r, err := Optimize2D(crabs, *metric, otherCost)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
+		end := time.Since(start)
+		fmt.Printf("With the %s metric and %s fuel costs, the optimum location is %v\n",
+			*metric, otherName, r)
+		fmt.Printf("Executed in %v\n", end)
+		return
+	}
+
+	inCrabs, err := ReadCrabs(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
 	// for _, n := range inCrabs {
@@ -35,10 +102,22 @@ func main() {
 
 	start := time.Now()
 
-	sort.Ints(inCrabs)
+	inCrabs = SortCrabs(inCrabs)
 	end := time.Since(start)
 	fmt.Printf("Sort time is %v\n", end)
 
+	if *verify {
+		ok := true
+		for _, v := range Verify(inCrabs) {
+			fmt.Println(v)
+			ok = ok && v.OK()
+		}
+		if !ok {
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Consider the leftmost and rightmost crabs. To bring those two
 	// crabs together at any position between them will cost the same
 	// amount of fuel, because a position 1 unit closer to the right
@@ -55,15 +134,7 @@ func main() {
 	// optimum. In short, the optimum place for crab meetups is the
 	// integer closest to the median position.
 	start = time.Now()
-	answer := inCrabs[len(inCrabs)/2]
-	fuelCost := 0
-	for _, pos := range inCrabs {
-		if answer > pos {
-			fuelCost += answer - pos
-		} else {
-			fuelCost += pos - answer
-		}
-	}
+	answer, fuelCost := medianHeuristic(inCrabs)
 	end = time.Since(start)
 	fmt.Printf("The optimum location is %d\n", answer)
 	fmt.Printf("The fuel cost is %d\n", fuelCost)
@@ -80,30 +151,102 @@ func main() {
 	start = time.Now()
 	fuelCosts := make(map[int]int, 3)
 	sum := 0
-	for _, pos := range inCrabs {
-		sum += pos
+	for _, c := range inCrabs {
+		sum += c.Weight * c.Pos
 	}
-	var mean int = int(math.Round(float64(sum) / float64(len(inCrabs))))
+	var mean int = int(math.Round(float64(sum) / float64(totalWeight(inCrabs))))
 	// compute a bit around the mean
 	for x := mean - 1; x <= mean+1; x++ {
 		fuelCosts[x] = fuelCostNonlinear(x, inCrabs)
 	}
 	end = time.Since(start)
 	fmt.Printf("The optimum location is close to %d\n", mean)
-	for x, c := range fuelCosts {
-		fmt.Printf("The fuel cost at %d is %d\n", x, c)
+	// in order of position, not the map's
+	for x := mean - 1; x <= mean+1; x++ {
+		fmt.Printf("The fuel cost at %d is %d\n", x, fuelCosts[x])
 	}
 
 	fmt.Printf("Executed in %v\n", end)
+
+	if cost != nil {
+		start = time.Now()
+		pos, fuel := Optimize(inCrabs, cost)
+		end = time.Since(start)
+		search := "binary search, as the cost is convex"
+		if !cost.Convex() {
+			search = "full scan, as the cost is not convex"
+		}
+		fmt.Printf("With %s fuel costs, the optimum location is %d at a fuel cost of %d\n",
+			*costName, pos, fuel)
+		fmt.Printf("Executed by %s in %v\n", search, end)
+	}
+
+	if *meetings > 0 {
+		start = time.Now()
+		clusters, err := Clusters(inCrabs, *meetings, otherCost)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		end = time.Since(start)
+		total := 0
+		for _, c := range clusters {
+			total += c.Fuel
+		}
+		fmt.Printf("With %d meeting points and %s fuel costs, the fuel cost is %d\n",
+			len(clusters), otherName, total)
+		for _, c := range clusters {
+			fmt.Printf("\t%d crabs meet at %d for %d fuel: %v\n", totalWeight(c.Crabs), c.Point, c.Fuel, c.Crabs)
+		}
+		fmt.Printf("Executed in %v\n", end)
+	}
+
+	if *curveFile != "" || *sparkline > 0 {
+		curve := CostCurve(inCrabs, otherCost)
+		err := writeFile(*curveFile, func(w io.Writer) error {
+			return WriteCurveCSV(w, inCrabs[0].Pos, curve)
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if *sparkline > 0 {
+			fmt.Printf("%s fuel from %d to %d:\n%s\n", otherName, inCrabs[0].Pos, inCrabs[len(inCrabs)-1].Pos,
+				Sparkline(curve, *sparkline))
+		}
+	}
+
+	if *budget >= 0 {
+		g := MaxGathered(inCrabs, otherCost, *budget)
+		fmt.Printf("With %s fuel costs, %d fuel gathers at most %d crabs, at %d for %d fuel\n",
+			otherName, *budget, g.Crabs, g.Point, g.Fuel)
+	}
+
+	if constraints != nil {
+		pos, fuel, ok := ConstrainedOptimum(inCrabs, otherCost, constraints)
+		if !ok {
+			fmt.Println("No meeting point satisfies the constraints")
+		} else {
+			fmt.Printf("With %s fuel costs and the constraints, the optimum location is %d at a fuel cost of %d\n",
+				otherName, pos, fuel)
+		}
+	}
+
+	if *breakdown {
+		pos, fuel := Optimize(inCrabs, otherCost)
+		fmt.Printf("With %s fuel costs, meeting at %d for %d fuel:\n", otherName, pos, fuel)
+		printBreakdown(Breakdown(inCrabs, pos, otherCost), fuel)
+	}
 }
 
-func fuelCostNonlinear(pos int, crabs []int) int {
+func fuelCostNonlinear(pos int, crabs []Crab) int {
 	var fuelCost int = 0
-	for _, x := range crabs {
+	for _, c := range crabs {
+		x := c.Pos
 		if x < pos {
-			fuelCost += (pos - x) * (pos - x + 1) / 2
+			fuelCost += c.Weight * (pos - x) * (pos - x + 1) / 2
 		} else {
-			fuelCost += (x - pos) * (x - pos + 1) / 2
+			fuelCost += c.Weight * (x - pos) * (x - pos + 1) / 2
 		}
 	}
 	return fuelCost
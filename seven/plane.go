@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Crabs on a plane. How far a crab has to go depends on the metric:
+//
+//	manhattan: |dx| + |dy|, moving along one axis at a time
+//	chebyshev: max(|dx|, |dy|), moving diagonally as cheaply as straight
+//	euclidean: the straight-line distance, so meeting points and fuel
+//	           need not be whole numbers
+//
+// With the manhattan metric the fuel cost applies to each axis on its
+// own, and with the others to the whole distance.
+
+type Point struct {
+	X, Y int
+}
+
+var Metrics = []string{"manhattan", "chebyshev", "euclidean"}
+
+// A Rendezvous is the best meeting point on the plane. With the
+// euclidean metric it needn't be a whole-number point, so Exact is set
+// and the point and fuel are in X, Y and ExactFuel instead.
+type Rendezvous struct {
+	Point Point
+	Fuel  int
+
+	Exact     bool
+	X, Y      float64
+	ExactFuel float64
+}
+
+func (r Rendezvous) String() string {
+	if r.Exact {
+		return fmt.Sprintf("(%.4f, %.4f) at a fuel cost of %.4f", r.X, r.Y, r.ExactFuel)
+	}
+	return fmt.Sprintf("(%d, %d) at a fuel cost of %d", r.Point.X, r.Point.Y, r.Fuel)
+}
+
+// Optimize2D finds the best meeting point for crabs on a plane
+func Optimize2D(crabs []Crab2D, metric string, cost FuelCost) (Rendezvous, error) {
+	if len(crabs) == 0 {
+		return Rendezvous{}, fmt.Errorf("no crabs to meet")
+	}
+	switch metric {
+	case "manhattan":
+		return manhattan(crabs, cost), nil
+	case "chebyshev":
+		return chebyshev(crabs, cost), nil
+	case "euclidean":
+		return euclidean(crabs, cost)
+	}
+	return Rendezvous{}, fmt.Errorf("unknown metric %q; try %v", metric, Metrics)
+}
+
+// manhattan separates into one problem per axis
+func manhattan(crabs []Crab2D, cost FuelCost) Rendezvous {
+	xs := make([]Crab, len(crabs))
+	ys := make([]Crab, len(crabs))
+	for i, c := range crabs {
+		xs[i], ys[i] = Crab{c.X, c.Weight}, Crab{c.Y, c.Weight}
+	}
+	x, xFuel := Optimize(SortCrabs(xs), cost)
+	y, yFuel := Optimize(SortCrabs(ys), cost)
+	return Rendezvous{Point: Point{x, y}, Fuel: xFuel + yFuel}
+}
+
+func chebyshevFuel(p Point, crabs []Crab2D, cost FuelCost) int {
+	total := 0
+	for _, c := range crabs {
+		dx, dy := p.X-c.X, p.Y-c.Y
+		if dx < 0 {
+			dx = -dx
+		}
+		if dy < 0 {
+			dy = -dy
+		}
+		if dx < dy {
+			dx = dy
+		}
+		total += c.Weight * cost.Fuel(dx)
+	}
+	return total
+}
+
+// chebyshev doesn't separate, but along any one column a convex cost of
+// the distance is still convex, so every column inside the crabs'
+// bounding box gets a binary search. A cost that isn't convex gets
+// every point of the box tried.
+func chebyshev(crabs []Crab2D, cost FuelCost) Rendezvous {
+	min, max := crabs[0].Point, crabs[0].Point
+	for _, c := range crabs {
+		if c.X < min.X {
+			min.X = c.X
+		}
+		if c.Y < min.Y {
+			min.Y = c.Y
+		}
+		if c.X > max.X {
+			max.X = c.X
+		}
+		if c.Y > max.Y {
+			max.Y = c.Y
+		}
+	}
+	best := Rendezvous{Point: min, Fuel: chebyshevFuel(min, crabs, cost)}
+	try := func(p Point) {
+		if f := chebyshevFuel(p, crabs, cost); f < best.Fuel {
+			best = Rendezvous{Point: p, Fuel: f}
+		}
+	}
+	for x := min.X; x <= max.X; x++ {
+		if !cost.Convex() {
+			for y := min.Y; y <= max.Y; y++ {
+				try(Point{x, y})
+			}
+			continue
+		}
+		lo, hi := min.Y, max.Y
+		for lo < hi {
+			mid := lo + (hi-lo)/2
+			if chebyshevFuel(Point{x, mid + 1}, crabs, cost) >= chebyshevFuel(Point{x, mid}, crabs, cost) {
+				hi = mid
+			} else {
+				lo = mid + 1
+			}
+		}
+		try(Point{x, lo})
+	}
+	return best
+}
+
+// fuelAt is a cost's fuel for a fractional distance, and its derivative
+func fuelAt(cost FuelCost, d float64) (fuel, slope float64, ok bool) {
+	switch cost.(type) {
+	case Linear:
+		return d, 1, true
+	case Triangular:
+		return d * (d + 1) / 2, d + 0.5, true
+	case Quadratic:
+		return d * d, 2 * d, true
+	}
+	return 0, 0, false
+}
+
+// euclidean finds the geometric median, or its equivalent for the other
+// costs, by Weiszfeld's iteration: each step moves to the average of
+// the crabs, each weighted by the cost's slope at its distance over
+// that distance, and by its own weight. For linear costs that is 1/d,
+// Weiszfeld's own.
+func euclidean(crabs []Crab2D, cost FuelCost) (Rendezvous, error) {
+	if _, _, ok := fuelAt(cost, 0); !ok {
+		return Rendezvous{}, fmt.Errorf("the euclidean metric needs a linear, triangular or quadratic cost")
+	}
+	total := func(x, y float64) float64 {
+		sum := 0.0
+		for _, c := range crabs {
+			f, _, _ := fuelAt(cost, math.Hypot(x-float64(c.X), y-float64(c.Y)))
+			sum += float64(c.Weight) * f
+		}
+		return sum
+	}
+	// start from the centroid
+	var x, y, n float64
+	for _, c := range crabs {
+		x += float64(c.Weight) * float64(c.X)
+		y += float64(c.Weight) * float64(c.Y)
+		n += float64(c.Weight)
+	}
+	x /= n
+	y /= n
+	for i := 0; i < 10000; i++ {
+		var sx, sy, sw float64
+		for _, c := range crabs {
+			d := math.Hypot(x-float64(c.X), y-float64(c.Y))
+			// sitting right on a crab would divide by zero
+			d = math.Max(d, 1e-9)
+			_, slope, _ := fuelAt(cost, d)
+			w := float64(c.Weight) * slope / d
+			sx += w * float64(c.X)
+			sy += w * float64(c.Y)
+			sw += w
+		}
+		nx, ny := sx/sw, sy/sw
+		moved := math.Hypot(nx-x, ny-y)
+		x, y = nx, ny
+		if moved < 1e-10 {
+			break
+		}
+	}
+	return Rendezvous{Exact: true, X: x, Y: y, ExactFuel: total(x, y)}, nil
+}
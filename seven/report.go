@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// CostCurve is the total fuel at every position from the leftmost crab
+// to the rightmost. crabs must be sorted.
+func CostCurve(crabs []Crab, cost FuelCost) []int {
+	table := NewPrefixTable(crabs)
+	curve := make([]int, table.Max-table.Min+1)
+	for i := range curve {
+		f, ok := table.Total(table.Min+i, cost)
+		if !ok {
+			f = TotalFuel(table.Min+i, crabs, cost)
+		}
+		curve[i] = f
+	}
+	return curve
+}
+
+// WriteCurveCSV writes a position,fuel row for each point of the curve,
+// which starts at position min
+func WriteCurveCSV(w io.Writer, min int, curve []int) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"position", "fuel"})
+	for i, f := range curve {
+		out.Write([]string{strconv.Itoa(min + i), strconv.Itoa(f)})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// sparkLevels run from the least fuel to the most
+const sparkLevels = "_.-=+*#@"
+
+// Sparkline draws the curve in at most width characters. Each character
+// stands for a run of positions and shows the cheapest of them, so the
+// optimum never gets averaged away.
+func Sparkline(curve []int, width int) string {
+	if len(curve) == 0 || width < 1 {
+		return ""
+	}
+	if width > len(curve) {
+		width = len(curve)
+	}
+	lo, hi := curve[0], curve[0]
+	for _, f := range curve {
+		if f < lo {
+			lo = f
+		}
+		if f > hi {
+			hi = f
+		}
+	}
+	line := make([]byte, width)
+	for col := range line {
+		from, to := col*len(curve)/width, (col+1)*len(curve)/width
+		least := curve[from]
+		for _, f := range curve[from:to] {
+			if f < least {
+				least = f
+			}
+		}
+		level := 0
+		if hi > lo {
+			level = (least - lo) * (len(sparkLevels) - 1) / (hi - lo)
+		}
+		line[col] = sparkLevels[level]
+	}
+	return string(line)
+}
+
+// A CrabFuel is the share of the fuel at a meeting point of the crabs
+// at one position
+type CrabFuel struct {
+	Position, Crabs, Distance, Fuel int
+}
+
+// Breakdown lists the trip to pos from every position with crabs on it,
+// the costliest first
+func Breakdown(crabs []Crab, pos int, cost FuelCost) []CrabFuel {
+	result := make([]CrabFuel, len(crabs))
+	for i, c := range crabs {
+		d := c.Pos - pos
+		if d < 0 {
+			d = -d
+		}
+		result[i] = CrabFuel{Position: c.Pos, Crabs: c.Weight, Distance: d,
+			Fuel: c.Weight * cost.Fuel(d)}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Fuel != result[j].Fuel {
+			return result[i].Fuel > result[j].Fuel
+		}
+		return result[i].Position < result[j].Position
+	})
+	return result
+}
+
+func printBreakdown(breakdown []CrabFuel, total int) {
+	fmt.Println("position\tcrabs\tdistance\tfuel\tshare")
+	for _, c := range breakdown {
+		share := 0.0
+		if total > 0 {
+			share = 100 * float64(c.Fuel) / float64(total)
+		}
+		fmt.Printf("%d\t\t%d\t%d\t\t%d\t%.1f%%\n", c.Position, c.Crabs, c.Distance, c.Fuel, share)
+	}
+}
+
+func writeFile(name string, write func(io.Writer) error) error {
+	if name == "" {
+		return nil
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// A Stream keeps the best linear-cost meeting point, the median, as
+// crabs come and go, without sorting anything. The crabs are split into
+// two heaps: the lower half, largest on top, and the upper half,
+// smallest on top, with the lower half holding the extra crab when
+// there's an odd number. The median is then the top of the lower half,
+// and keeping the sum of each half gives the fuel too:
+//
+//	fuel = (median*len(low) - sum(low)) + (sum(high) - median*len(high))
+//
+// A crab that leaves is not dug out of its heap straight away; it is
+// just noted in delayed, and thrown away once it reaches the top.
+type Stream struct {
+	low, high         intHeap
+	lowSize, highSize int // the crabs still here, not counting delayed ones
+	lowSum, highSum   int
+	counts, delayed   map[int]int
+}
+
+// an intHeap is a min-heap; the lower half stores negated positions to
+// make it a max-heap
+type intHeap []int
+
+func (h intHeap) Len() int            { return len(h) }
+func (h intHeap) Less(i, j int) bool  { return h[i] < h[j] }
+func (h intHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *intHeap) Push(x interface{}) { *h = append(*h, x.(int)) }
+func (h *intHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+func NewStream() *Stream {
+	return &Stream{counts: make(map[int]int), delayed: make(map[int]int)}
+}
+
+func (s *Stream) Len() int {
+	return s.lowSize + s.highSize
+}
+
+func (s *Stream) lowTop() int  { return -s.low[0] }
+func (s *Stream) highTop() int { return s.high[0] }
+
+// Arrive adds a crab at pos
+func (s *Stream) Arrive(pos int) {
+	s.counts[pos]++
+	if s.lowSize == 0 || pos <= s.lowTop() {
+		heap.Push(&s.low, -pos)
+		s.lowSize++
+		s.lowSum += pos
+	} else {
+		heap.Push(&s.high, pos)
+		s.highSize++
+		s.highSum += pos
+	}
+	s.balance()
+}
+
+// Depart removes a crab at pos
+func (s *Stream) Depart(pos int) error {
+	if s.counts[pos] == 0 {
+		return fmt.Errorf("there is no crab at %d to leave", pos)
+	}
+	s.counts[pos]--
+	s.delayed[pos]++
+	// everything in the lower half is at most its top, so a crab at or
+	// below it can be taken from there; crabs at the same position are
+	// interchangeable
+	if pos <= s.lowTop() {
+		s.lowSize--
+		s.lowSum -= pos
+		if pos == s.lowTop() {
+			s.prune()
+		}
+	} else {
+		s.highSize--
+		s.highSum -= pos
+		if pos == s.highTop() {
+			s.prune()
+		}
+	}
+	s.balance()
+	return nil
+}
+
+// prune throws away departed crabs from the tops of the heaps
+func (s *Stream) prune() {
+	for len(s.low) > 0 && s.delayed[s.lowTop()] > 0 {
+		s.delayed[s.lowTop()]--
+		heap.Pop(&s.low)
+	}
+	for len(s.high) > 0 && s.delayed[s.highTop()] > 0 {
+		s.delayed[s.highTop()]--
+		heap.Pop(&s.high)
+	}
+}
+
+// balance moves a crab between halves until the lower half has the
+// same number as the upper half, or one more
+func (s *Stream) balance() {
+	for s.lowSize > s.highSize+1 {
+		pos := -heap.Pop(&s.low).(int)
+		heap.Push(&s.high, pos)
+		s.lowSize--
+		s.lowSum -= pos
+		s.highSize++
+		s.highSum += pos
+		s.prune()
+	}
+	for s.lowSize < s.highSize {
+		pos := heap.Pop(&s.high).(int)
+		heap.Push(&s.low, -pos)
+		s.highSize--
+		s.highSum -= pos
+		s.lowSize++
+		s.lowSum += pos
+		s.prune()
+	}
+}
+
+// Median is the best linear-cost meeting point, the leftmost if there
+// are several. ok is false when there are no crabs.
+func (s *Stream) Median() (pos int, ok bool) {
+	if s.lowSize == 0 {
+		return 0, false
+	}
+	return s.lowTop(), true
+}
+
+// Fuel is the least total linear fuel for the crabs here now
+func (s *Stream) Fuel() int {
+	m, ok := s.Median()
+	if !ok {
+		return 0
+	}
+	return m*s.lowSize - s.lowSum + s.highSum - m*s.highSize
+}
+
+// RunStream reads crab arrivals and departures and writes the best
+// meeting point after each one. An update is a position, which arrives,
+// or a position with a leading + or -, which arrives or leaves; so a
+// crab at -3 is written +-3 or --3. Updates are separated like the
+// input to ReadCrabs.
+func RunStream(r io.Reader, w io.Writer) error {
+	s := NewStream()
+	isSep := func(c byte) bool { return c == ',' || isSpace(c) }
+	return scanTokens(r, isSep, func(lineNo, column int, token string) error {
+		leave := false
+		posStr := token
+		switch token[0] {
+		case '+':
+			posStr = token[1:]
+		case '-':
+			leave, posStr = true, token[1:]
+		}
+		pos, err := strconv.Atoi(posStr)
+		if err != nil {
+			return fmt.Errorf("line %d, column %d: %q is not an update", lineNo, column, token)
+		}
+		verb := "arrives at"
+		if leave {
+			verb = "leaves"
+			if err := s.Depart(pos); err != nil {
+				return fmt.Errorf("line %d, column %d: %v", lineNo, column, err)
+			}
+		} else {
+			s.Arrive(pos)
+		}
+		if m, ok := s.Median(); ok {
+			fmt.Fprintf(w, "a crab %s %d: %d crabs meet at %d for %d fuel\n",
+				verb, pos, s.Len(), m, s.Fuel())
+		} else {
+			fmt.Fprintf(w, "a crab %s %d: no crabs left\n", verb, pos)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// The median and mean shortcuts in main are argued on paper. Here they
+// are checked the dumb way, against the fuel at every position from the
+// leftmost crab to the rightmost.
+
+// medianHeuristic is part one's answer: the median crab, counting a
+// weighted crab as that many crabs
+func medianHeuristic(crabs []Crab) (pos, fuel int) {
+	half := totalWeight(crabs) / 2
+	for _, c := range crabs {
+		pos = c.Pos
+		if half -= c.Weight; half < 0 {
+			break
+		}
+	}
+	return pos, TotalFuel(pos, crabs, Linear{})
+}
+
+// meanHeuristic is part two's answer: the best of the positions around
+// the mean
+func meanHeuristic(crabs []Crab) (pos, fuel int) {
+	sum := 0
+	for _, c := range crabs {
+		sum += c.Weight * c.Pos
+	}
+	mean := int(math.Round(float64(sum) / float64(totalWeight(crabs))))
+	pos, fuel = mean, fuelCostNonlinear(mean, crabs)
+	for x := mean - 1; x <= mean+1; x++ {
+		if f := fuelCostNonlinear(x, crabs); f < fuel {
+			pos, fuel = x, f
+		}
+	}
+	return pos, fuel
+}
+
+// A PrefixTable gives the total fuel at any position in O(1), for costs
+// that are polynomials in the distance. For each position it keeps how
+// many crabs are to its left, and the sums of their positions and
+// squared positions; everything to the right is the grand total less
+// that.
+type PrefixTable struct {
+	Min, Max            int
+	count, sum, sumSq   []int // over crabs at positions < Min+i
+	total, tSum, tSumSq int
+}
+
+// NewPrefixTable builds the table in O(n + range). crabs must be sorted.
+func NewPrefixTable(crabs []Crab) *PrefixTable {
+	t := &PrefixTable{Min: crabs[0].Pos, Max: crabs[len(crabs)-1].Pos}
+	size := t.Max - t.Min + 2
+	t.count = make([]int, size)
+	t.sum = make([]int, size)
+	t.sumSq = make([]int, size)
+	for _, c := range crabs {
+		i := c.Pos - t.Min + 1
+		t.count[i] += c.Weight
+		t.sum[i] += c.Weight * c.Pos
+		t.sumSq[i] += c.Weight * c.Pos * c.Pos
+	}
+	for i := 1; i < size; i++ {
+		t.count[i] += t.count[i-1]
+		t.sum[i] += t.sum[i-1]
+		t.sumSq[i] += t.sumSq[i-1]
+	}
+	t.total, t.tSum, t.tSumSq = t.count[size-1], t.sum[size-1], t.sumSq[size-1]
+	return t
+}
+
+// Total is TotalFuel for positions from Min to Max. ok is false for
+// costs the table can't do.
+func (t *PrefixTable) Total(pos int, cost FuelCost) (fuel int, ok bool) {
+	i := pos - t.Min
+	// crabs left of pos, then those at or right of it
+	c, s, q := t.count[i], t.sum[i], t.sumSq[i]
+	cr, sr, qr := t.total-c, t.tSum-s, t.tSumSq-q
+	// sums of distances and squared distances
+	d := pos*c - s + sr - pos*cr
+	d2 := pos*pos*c - 2*pos*s + q + qr - 2*pos*sr + pos*pos*cr
+	switch cost.(type) {
+	case Linear:
+		return d, true
+	case Triangular:
+		return (d2 + d) / 2, true
+	case Quadratic:
+		return d2, true
+	}
+	return 0, false
+}
+
+// Best scans every position for the least fuel, the leftmost on ties
+func (t *PrefixTable) Best(cost FuelCost) (pos, fuel int, ok bool) {
+	for x := t.Min; x <= t.Max; x++ {
+		f, ok := t.Total(x, cost)
+		if !ok {
+			return 0, 0, false
+		}
+		if x == t.Min || f < fuel {
+			pos, fuel = x, f
+		}
+	}
+	return pos, fuel, true
+}
+
+// A Verification compares one shortcut with the brute-force optimum.
+// Several positions can tie, so only the fuel has to agree.
+type Verification struct {
+	Name                     string
+	Heuristic, HeuristicFuel int
+	Best, BestFuel           int
+}
+
+func (v Verification) OK() bool {
+	return v.HeuristicFuel == v.BestFuel
+}
+
+func (v Verification) String() string {
+	verdict := "agrees"
+	if !v.OK() {
+		verdict = "MISMATCH"
+	}
+	return fmt.Sprintf("%s: shortcut %d costs %d, brute force %d costs %d -- %s",
+		v.Name, v.Heuristic, v.HeuristicFuel, v.Best, v.BestFuel, verdict)
+}
+
+// Verify checks the median, the mean and Optimize against the prefix
+// table's brute force. crabs must be sorted.
+func Verify(crabs []Crab) []Verification {
+	table := NewPrefixTable(crabs)
+	var result []Verification
+	check := func(name string, cost FuelCost, pos, fuel int) {
+		best, bestFuel, _ := table.Best(cost)
+		result = append(result, Verification{name, pos, fuel, best, bestFuel})
+	}
+	pos, fuel := medianHeuristic(crabs)
+	check("median, linear", Linear{}, pos, fuel)
+	pos, fuel = meanHeuristic(crabs)
+	check("mean, triangular", Triangular{}, pos, fuel)
+	for _, name := range []string{"linear", "triangular", "quadratic"} {
+		pos, fuel = Optimize(crabs, FuelCosts[name])
+		check("optimizer, "+name, FuelCosts[name], pos, fuel)
+	}
+
+	// the stream takes crabs one at a time, so it gets one crab from
+	// each position, arriving and then every other one leaving again
+	s := NewStream()
+	var unit []Crab
+	for _, c := range crabs {
+		s.Arrive(c.Pos)
+		unit = append(unit, Crab{c.Pos, 1})
+	}
+	pos, _ = s.Median()
+	best, bestFuel, _ := NewPrefixTable(unit).Best(Linear{})
+	result = append(result, Verification{"stream, linear", pos, s.Fuel(), best, bestFuel})
+	if len(unit) > 1 {
+		var stay []Crab
+		for i, c := range unit {
+			if i%2 == 0 {
+				stay = append(stay, c)
+			} else {
+				s.Depart(c.Pos)
+			}
+		}
+		pos, _ = s.Median()
+		best, bestFuel, _ := NewPrefixTable(stay).Best(Linear{})
+		result = append(result, Verification{"stream after departures, linear", pos, s.Fuel(), best, bestFuel})
+	}
+	return result
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// randomCrabs makes a sorted swarm of up to most crabs, bunched or
+// spread out at random so that the odd outlier turns up. Half the
+// swarms are weighted, with weights up to heaviest.
+func randomCrabs(rng *rand.Rand, most, maxPos, heaviest int) []Crab {
+	crabs := make([]Crab, 1+rng.Intn(most))
+	spread := 1 + rng.Intn(maxPos)
+	maxWeight := 1
+	if rng.Intn(2) == 0 {
+		maxWeight = 1 + rng.Intn(heaviest)
+	}
+	for i := range crabs {
+		crabs[i] = Crab{rng.Intn(spread), 1 + rng.Intn(maxWeight)}
+	}
+	return SortCrabs(crabs)
+}
+
+// copies writes a weighted crab out as that many crabs of weight 1,
+// the way ReadCrabs used to store them
+func copies(crabs []Crab) []Crab {
+	var result []Crab
+	for _, c := range crabs {
+		for i := 0; i < c.Weight; i++ {
+			result = append(result, Crab{c.Pos, 1})
+		}
+	}
+	return result
+}
+
+func TestVerifyExample(t *testing.T) {
+	crabs, err := ReadCrabs(strings.NewReader("16,1,2,0,4,2,7,1,2,14"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	crabs = SortCrabs(crabs)
+	if pos, fuel := medianHeuristic(crabs); pos != 2 || fuel != 37 {
+		t.Errorf("median: %d fuel at %d, want 37 at 2", fuel, pos)
+	}
+	if pos, fuel := meanHeuristic(crabs); pos != 5 || fuel != 168 {
+		t.Errorf("mean: %d fuel at %d, want 168 at 5", fuel, pos)
+	}
+	for _, v := range Verify(crabs) {
+		if !v.OK() {
+			t.Error(v)
+		}
+	}
+}
+
+func TestVerifyRandomSwarms(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for round := 0; round < 2000; round++ {
+		crabs := randomCrabs(rng, 50, 2000, 1000)
+		for _, v := range Verify(crabs) {
+			if !v.OK() {
+				t.Fatalf("swarm %v: %v", crabs, v)
+			}
+		}
+	}
+}
+
+// A weighted crab has to give the same answers as that many copies
+func TestWeightsMatchCopies(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	costs := []FuelCost{Linear{}, Triangular{}, Quadratic{}, Capped{Linear{}, 20}}
+	constraints := &Constraints{Forbidden: []Span{{40, 60}}}
+	for round := 0; round < 200; round++ {
+		crabs := randomCrabs(rng, 12, 100, 10)
+		unit := copies(crabs)
+		sort.SliceStable(unit, func(i, j int) bool { return unit[i].Pos < unit[j].Pos })
+		for _, cost := range costs {
+			pos, fuel := Optimize(crabs, cost)
+			if wantPos, wantFuel := Optimize(unit, cost); pos != wantPos || fuel != wantFuel {
+				t.Fatalf("%v, %T: optimum %d at %d, copies give %d at %d",
+					crabs, cost, fuel, pos, wantFuel, wantPos)
+			}
+			if got, want := MaxGathered(crabs, cost, 500), MaxGathered(unit, cost, 500); got != want {
+				t.Fatalf("%v, %T: gathered %+v, copies gather %+v", crabs, cost, got, want)
+			}
+			pos, fuel, ok := ConstrainedOptimum(crabs, cost, constraints)
+			wantPos, wantFuel, wantOK := ConstrainedOptimum(unit, cost, constraints)
+			if pos != wantPos || fuel != wantFuel || ok != wantOK {
+				t.Fatalf("%v, %T: constrained optimum %d at %d, copies give %d at %d",
+					crabs, cost, fuel, pos, wantFuel, wantPos)
+			}
+			total := 0
+			for _, f := range Breakdown(crabs, pos, cost) {
+				total += f.Fuel
+			}
+			if want := TotalFuel(pos, unit, cost); total != want {
+				t.Fatalf("%v, %T: breakdown adds up to %d, want %d", crabs, cost, total, want)
+			}
+			if _, ok := cost.(Capped); ok {
+				continue
+			}
+			table, unitTable := NewPrefixTable(crabs), NewPrefixTable(unit)
+			for x := table.Min; x <= table.Max; x++ {
+				got, _ := table.Total(x, cost)
+				want, _ := unitTable.Total(x, cost)
+				if got != want {
+					t.Fatalf("%v, %T: prefix table has %d at %d, copies %d", crabs, cost, got, x, want)
+				}
+			}
+			for k := 1; k <= 3; k++ {
+				clusters, err := Clusters(crabs, k, cost)
+				if err != nil {
+					t.Fatal(err)
+				}
+				unitClusters, err := Clusters(unit, k, cost)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, want := clusterFuel(clusters), clusterFuel(unitClusters); got != want {
+					t.Fatalf("%v, %T: %d clusters cost %d, copies %d", crabs, cost, k, got, want)
+				}
+			}
+		}
+	}
+}
+
+func clusterFuel(clusters []Cluster) int {
+	total := 0
+	for _, c := range clusters {
+		total += c.Fuel
+	}
+	return total
+}
+
+func TestWeightsMatchCopies2D(t *testing.T) {
+	crabs := []Crab2D{{Point{0, 0}, 3}, {Point{5, 1}, 1}, {Point{2, 7}, 2}}
+	var unit []Crab2D
+	for _, c := range crabs {
+		for i := 0; i < c.Weight; i++ {
+			unit = append(unit, Crab2D{c.Point, 1})
+		}
+	}
+	for _, metric := range Metrics {
+		got, err := Optimize2D(crabs, metric, Triangular{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := Optimize2D(unit, metric, Triangular{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("%s: got %v, copies give %v", metric, got, want)
+		}
+	}
+}
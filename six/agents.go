@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// The bucket models never look at a single fish, which is what makes
+// them fast, but also what makes them easy to get subtly wrong. Here
+// every fish is its own little struct, simulated the long way round, so
+// that the buckets have something independent to be checked against.
+
+// maxAgents keeps a per-fish simulation from eating all the memory: the
+// puzzle's 256 days would need tens of billions of fish
+const maxAgents = 50_000_000
+
+type Fish struct {
+	Timer int
+	Age   int
+}
+
+// AgentOptions makes the per-fish simulation stochastic. The zero value
+// is deterministic and follows the Model exactly.
+type AgentOptions struct {
+	// after spawning, a fish waits up to Jitter extra days, chosen
+	// uniformly, before its next spawn
+	Jitter int
+	// each fish dies on any given day with this probability, after it
+	// has had the chance to spawn
+	Mortality float64
+	Rand      *rand.Rand
+}
+
+func (o AgentOptions) Deterministic() bool {
+	return o.Jitter == 0 && o.Mortality == 0
+}
+
+// SimulateAgents runs one fish at a time from byTimer for the given
+// number of days, and returns the count at each timer value at the end.
+// Fish with a lifespan start on their first cycle, as in NewPopulation.
+func (m Model) SimulateAgents(byTimer []int, days int, opts AgentOptions) ([]int, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	if len(byTimer) > m.Timers() {
+		return nil, fmt.Errorf("timers run from 0 to %d, but there are fish with timer %d",
+			m.Timers()-1, len(byTimer)-1)
+	}
+	if opts.Jitter < 0 || opts.Mortality < 0 || opts.Mortality > 1 {
+		return nil, fmt.Errorf("jitter must be non-negative and mortality a probability, not %+v", opts)
+	}
+	if !opts.Deterministic() && opts.Rand == nil {
+		return nil, fmt.Errorf("a stochastic simulation needs a random source")
+	}
+	var school []Fish
+	for timer, n := range byTimer {
+		age := m.ageOf(timer)
+		if m.Lifespan > 0 && age >= m.Lifespan {
+			continue
+		}
+		for i := 0; i < n; i++ {
+			school = append(school, Fish{Timer: timer, Age: age})
+		}
+	}
+
+	for day := 1; day <= days; day++ {
+		// newborns go on the end, after the fish that are counted today
+		alive := len(school)
+		next := school[:0]
+		var births int
+		for _, f := range school[:alive] {
+			if f.Timer == 0 {
+				births++
+				f.Timer = m.Cycle - 1
+				if opts.Jitter > 0 {
+					f.Timer += opts.Rand.Intn(opts.Jitter + 1)
+				}
+			} else {
+				f.Timer--
+			}
+			f.Age++
+			if m.Lifespan > 0 && f.Age >= m.Lifespan {
+				continue
+			}
+			if opts.Mortality > 0 && opts.Rand.Float64() < opts.Mortality {
+				continue
+			}
+			next = append(next, f)
+		}
+		if len(next)+births > maxAgents {
+			return nil, fmt.Errorf("more than %d fish on day %d; too many to simulate one by one",
+				maxAgents, day)
+		}
+		for i := 0; i < births; i++ {
+			next = append(next, Fish{Timer: m.Timers() - 1})
+		}
+		school = next
+	}
+
+	// a jittered timer can run past the newborn timer, so size the
+	// buckets to fit
+	var buckets []int
+	for _, f := range school {
+		for len(buckets) <= f.Timer {
+			buckets = append(buckets, 0)
+		}
+		buckets[f.Timer]++
+	}
+	for len(buckets) < m.Timers() {
+		buckets = append(buckets, 0)
+	}
+	return buckets, nil
+}
+
+// AgentStats summarizes the final population over many stochastic runs
+type AgentStats struct {
+	Runs     int
+	Mean     float64
+	Variance float64 // the sample variance
+	Min, Max int
+}
+
+func (s AgentStats) StdDev() float64 {
+	return math.Sqrt(s.Variance)
+}
+
+// AgentRuns repeats SimulateAgents and collects the final totals
+func (m Model) AgentRuns(byTimer []int, days int, opts AgentOptions, runs int) (AgentStats, error) {
+	stats := AgentStats{Runs: runs}
+	// Welford's running mean and variance, which doesn't lose precision
+	// when the totals are large and close together
+	var sumSquares float64
+	for run := 1; run <= runs; run++ {
+		buckets, err := m.SimulateAgents(byTimer, days, opts)
+		if err != nil {
+			return stats, err
+		}
+		total := 0
+		for _, n := range buckets {
+			total += n
+		}
+		if run == 1 || total < stats.Min {
+			stats.Min = total
+		}
+		if run == 1 || total > stats.Max {
+			stats.Max = total
+		}
+		delta := float64(total) - stats.Mean
+		stats.Mean += delta / float64(run)
+		sumSquares += delta * (float64(total) - stats.Mean)
+	}
+	if runs > 1 {
+		stats.Variance = sumSquares / float64(runs-1)
+	}
+	return stats, nil
+}
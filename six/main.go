@@ -2,25 +2,122 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
+	"io"
+	"math/big"
+	"math/rand"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// -exceed gives up after this many days, in case the fish die out
+const maxSearchDays = 100000
+
 func printBuckets(buckets []int, t int) {
 	fmt.Printf("t=%d:\t", t)
-	total := 0
+	// every bucket fits in an int, but the total might not
+	total := new(big.Int)
 	for _, n := range buckets {
 		fmt.Printf("%d\t", n)
-		total += n
+		total.Add(total, big.NewInt(int64(n)))
+	}
+	fmt.Printf(" -- total %v\n", total)
+}
+
+// the 'slow' way from the puzzle, kept as the reference that the
+// other algorithms are checked against
+func simulateSlow(inBuckets []int, endTime int) []int {
+	buckets := make([]int, 9)
+	copy(buckets, inBuckets)
+	for t := 1; t <= endTime; t++ {
+		births := buckets[0]
+		for i := range buckets {
+			if i == 8 {
+				buckets[i] = births
+				continue
+			}
+			buckets[i] = buckets[i+1]
+		}
+		buckets[6] += births
+	}
+	return buckets
+}
+
+func runEcosystem(speciesFile string, endTime int) error {
+	f, err := os.Open(speciesFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	eco, err := ParseEcosystem(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", speciesFile, err)
+	}
+	byTimer, err := eco.ReadTimers(os.Stdin)
+	if err != nil {
+		return err
 	}
-	fmt.Printf(" -- total %d\n", total)
+	community, err := eco.NewCommunity(byTimer)
+	if err != nil {
+		return err
+	}
+	community.Print()
+	start := time.Now()
+	community.Advance(endTime)
+	end := time.Since(start)
+	community.Print()
+	fmt.Printf("Executed the community in %v\n", end)
+	return nil
 }
 
 func main() {
-	inBuckets := make([]int, 9)
+	endTime := flag.Int("days", 256, "how many days to simulate")
+	cycle := flag.Int("cycle", Lanternfish.Cycle, "days between spawns")
+	delay := flag.Int("delay", Lanternfish.Delay, "extra days before a newborn first spawns")
+	lifespan := flag.Int("lifespan", 0, "age in days at which fish die (0 for never)")
+	project := flag.Bool("project", false,
+		"jump straight to -days by matrix exponentiation, for huge horizons")
+	modulus := flag.String("mod", "",
+		"with -project, give counts modulo this (ideally prime) number")
+	csvFile := flag.String("csv", "", "write every day's buckets, from 0 to -days, to this CSV file")
+	jsonFile := flag.String("json", "", "write every day's buckets, from 0 to -days, to this JSON file")
+	exceed := flag.String("exceed", "",
+		"find the first day on which the population is larger than this")
+	best := flag.Int("best", 0,
+		"find the starting timers for this many fish that give the most fish after -days")
+	agents := flag.Bool("agents", false,
+		"simulate every fish individually instead of in buckets, which is slow but independent")
+	jitter := flag.Int("jitter", 0,
+		"with -agents, fish wait up to this many extra days, at random, between spawns")
+	mortality := flag.Float64("mortality", 0,
+		"with -agents, the chance that a fish dies on any given day")
+	runs := flag.Int("runs", 100, "with -agents, how many stochastic runs to summarize")
+	seed := flag.Int64("seed", 1, "random seed for -agents")
+	speciesFile := flag.String("species", "",
+		"read several species and their rules from this file, and their timers from stdin")
+	flag.Parse()
+	model := Model{Cycle: *cycle, Delay: *delay, Lifespan: *lifespan}
+	var mod *big.Int
+	if *modulus != "" {
+		var ok bool
+		if mod, ok = new(big.Int).SetString(*modulus, 10); !ok || mod.Sign() <= 0 {
+			fmt.Fprintf(os.Stderr, "-mod must be a positive integer, not %q\n", *modulus)
+			os.Exit(2)
+		}
+	}
+
+	if *speciesFile != "" {
+		if err := runEcosystem(*speciesFile, *endTime); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	var inBuckets []int
 	scanner := bufio.NewScanner(os.Stdin)
 	for scanner.Scan() {
 		for _, n := range strings.Split(scanner.Text(), `,`) {
@@ -28,49 +125,144 @@ func main() {
 			if err != nil {
 				panic(err)
 			}
+			for len(inBuckets) <= i {
+				inBuckets = append(inBuckets, 0)
+			}
 			inBuckets[i]++
 		}
 	}
-	printBuckets(inBuckets, 0)
 
-	buckets := make([]int, 9)
-	for i, n := range inBuckets {
-		buckets[i] = n
+	if *agents {
+		opts := AgentOptions{
+			Jitter:    *jitter,
+			Mortality: *mortality,
+			Rand:      rand.New(rand.NewSource(*seed)),
+		}
+		start := time.Now()
+		if opts.Deterministic() {
+			buckets, err := model.SimulateAgents(inBuckets, *endTime, opts)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			printBuckets(buckets, *endTime)
+			fmt.Printf("Executed the per-fish simulation in %v\n", time.Since(start))
+			return
+		}
+		stats, err := model.AgentRuns(inBuckets, *endTime, opts, *runs)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("t=%d over %d runs: mean %.2f, variance %.2f (std dev %.2f), min %d, max %d\n",
+			*endTime, stats.Runs, stats.Mean, stats.Variance, stats.StdDev(), stats.Min, stats.Max)
+		fmt.Printf("Executed the per-fish simulations in %v\n", time.Since(start))
+		return
+	}
+
+	if *project {
+		start := time.Now()
+		buckets, err := model.Project(inBuckets, *endTime, mod)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		end := time.Since(start)
+		printBigBuckets(buckets, *endTime, mod)
+		fmt.Printf("Executed the matrix projection in %v\n", end)
+		return
+	}
+
+	if *exceed != "" {
+		n, ok := new(big.Int).SetString(*exceed, 10)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "-exceed must be an integer, not %q\n", *exceed)
+			os.Exit(2)
+		}
+		day, ok, err := model.FirstDayExceeding(inBuckets, n, maxSearchDays)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if !ok {
+			fmt.Printf("The population never exceeds %v\n", n)
+			return
+		}
+		fmt.Printf("The population first exceeds %v on day %d\n", n, day)
+		return
+	}
+
+	if *best > 0 {
+		byTimer, total, err := model.BestStart(*best, *endTime)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		printBuckets(byTimer, 0)
+		fmt.Printf("This start gives the most fish at t=%d: %s\n", *endTime, abbreviate(total))
+		return
+	}
+
+	if *csvFile != "" || *jsonFile != "" {
+		timeline, err := model.Timeline(inBuckets, *endTime)
+		if err == nil {
+			err = writeFile(*csvFile, func(w io.Writer) error { return WriteTimelineCSV(w, timeline) })
+		}
+		if err == nil {
+			err = writeFile(*jsonFile, func(w io.Writer) error { return WriteTimelineJSON(w, timeline) })
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 	}
 
-	// first, a fast but not ultimately-fast algorithm:
+	pop, err := model.NewPopulation(inBuckets)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	printBuckets(pop.Buckets(), 0)
 
-	endTime := 256
+	// the population model notices if the counts outgrow an int, so
+	// run it first to see whether the plain int loops can be trusted
 	start := time.Now()
-	for t := 1; t <= endTime; t++ {
-		births := buckets[0]
-		for i := range buckets {
-			if i == 8 {
-				buckets[i] = births
-				continue
-			}
-			buckets[i] = buckets[i+1]
+	pop.Advance(*endTime)
+	modelTime := time.Since(start)
+
+	if model == Lanternfish && pop.OverflowDay == 0 {
+		// first, a fast but not ultimately-fast algorithm:
+
+		start := time.Now()
+		buckets := simulateSlow(inBuckets, *endTime)
+		end := time.Since(start)
+		printBuckets(buckets, *endTime)
+		fmt.Printf("Executed the 'slow' way in %v\n", end)
+
+		// Now the even faster way, with fewer copies --
+		// this has successfully made me miss assembly language programming
+		buckets = make([]int, 9)
+		copy(buckets, inBuckets)
+		start = time.Now()
+		// why copy all those buckets? they mostly just cascade, so
+		// just move a cursor which always points to the bucket that is 0 at time t
+		cursor := 0
+		for t := 1; t <= *endTime; t++ {
+			buckets[(cursor+7)%9] += buckets[cursor]
+			cursor = (cursor + 1) % 9
 		}
-		buckets[6] += births
+		end = time.Since(start)
+		printBuckets(buckets, *endTime)
+		fmt.Printf("Executed the 'improved' way in %v\n", end)
 	}
-	end := time.Since(start)
-	printBuckets(buckets, endTime)
-	fmt.Printf("Executed the 'slow' way in %v\n", end)
-
-	// Now the even faster way, with fewer copies --
-	// this has successfully made me miss assembly language programming
-	for i, n := range inBuckets {
-		buckets[i] = n
-	}
-	start = time.Now()
-	// why copy all those buckets? they mostly just cascade, so
-	// just move a cursor which always points to the bucket that is 0 at time t
-	cursor := 0
-	for t := 1; t <= endTime; t++ {
-		buckets[(cursor+7)%9] += buckets[cursor]
-		cursor = (cursor + 1) % 9
+
+	// and the same cursor trick, generalized to any model
+	if pop.OverflowDay > 0 {
+		fmt.Printf("Counts overflow an int on day %d, so the population model switched to math/big\n",
+			pop.OverflowDay)
+		printBigBuckets(pop.BigBuckets(), *endTime, nil)
+	} else {
+		printBuckets(pop.Buckets(), *endTime)
 	}
-	end = time.Since(start)
-	printBuckets(buckets, endTime)
-	fmt.Printf("Executed the 'improved' way in %v\n", end)
+	fmt.Printf("Executed the population model in %v\n", modelTime)
 }
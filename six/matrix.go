@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Each day is the same linear map from one day's buckets to the next,
+// so t days is that map's matrix raised to the t-th power. Squaring
+// repeatedly gets there in O(log t) matrix products, which makes
+// t = 10^6 quick with exact counts. For t far beyond that the counts
+// themselves have too many digits to write down, but they can still be
+// computed modulo a prime.
+
+// a square matrix of big.Ints, indexed [row][col]
+type matrix [][]*big.Int
+
+func newMatrix(size int) matrix {
+	m := make(matrix, size)
+	for i := range m {
+		m[i] = make([]*big.Int, size)
+		for j := range m[i] {
+			m[i][j] = new(big.Int)
+		}
+	}
+	return m
+}
+
+func identity(size int) matrix {
+	m := newMatrix(size)
+	for i := range m {
+		m[i][i].SetInt64(1)
+	}
+	return m
+}
+
+// mul returns a×b, reduced modulo mod unless mod is nil
+func (a matrix) mul(b matrix, mod *big.Int) matrix {
+	result := newMatrix(len(a))
+	var term big.Int
+	for i := range a {
+		for k := range b {
+			if a[i][k].Sign() == 0 {
+				continue
+			}
+			for j := range b[k] {
+				term.Mul(a[i][k], b[k][j])
+				result[i][j].Add(result[i][j], &term)
+			}
+		}
+		if mod != nil {
+			for j := range result[i] {
+				result[i][j].Mod(result[i][j], mod)
+			}
+		}
+	}
+	return result
+}
+
+func (a matrix) pow(t int, mod *big.Int) matrix {
+	result := identity(len(a))
+	for base := a; t > 0; t >>= 1 {
+		if t&1 == 1 {
+			result = result.mul(base, mod)
+		}
+		if t > 1 {
+			base = base.mul(base, mod)
+		}
+	}
+	return result
+}
+
+// transition is the matrix that takes one day's ring of buckets to the
+// next, in the same layout that Population uses with its cursor at 0:
+// by timer without a lifespan, by age with one
+func (m Model) transition() matrix {
+	if m.Lifespan == 0 {
+		t := newMatrix(m.Timers())
+		for i := 0; i+1 < m.Timers(); i++ {
+			t[i][i+1].SetInt64(1)
+		}
+		t[m.Timers()-1][0].SetInt64(1)
+		t[m.Cycle-1][0].Add(t[m.Cycle-1][0], big.NewInt(1))
+		return t
+	}
+	t := newMatrix(m.Lifespan)
+	for age := 0; age+1 < m.Lifespan; age++ {
+		t[age+1][age].SetInt64(1)
+	}
+	for age := m.Timers() - 1; age < m.Lifespan; age += m.Cycle {
+		t[0][age].SetInt64(1)
+	}
+	return t
+}
+
+// Project returns the count of fish at each timer value after the
+// given number of days, reduced modulo mod unless mod is nil
+func (m Model) Project(byTimer []int, days int, mod *big.Int) ([]*big.Int, error) {
+	if days < 0 {
+		return nil, fmt.Errorf("cannot project %d days into the past", days)
+	}
+	pop, err := m.NewPopulation(byTimer)
+	if err != nil {
+		return nil, err
+	}
+	power := m.transition().pow(days, mod)
+	result := make([]*big.Int, m.Timers())
+	for i := range result {
+		result[i] = new(big.Int)
+	}
+	var term big.Int
+	for i, row := range power {
+		var n big.Int
+		for j, coeff := range row {
+			term.Mul(coeff, big.NewInt(int64(pop.ring[j])))
+			n.Add(&n, &term)
+		}
+		timer := i
+		if m.Lifespan > 0 {
+			timer = m.timerOf(i)
+		}
+		result[timer].Add(result[timer], &n)
+	}
+	if mod != nil {
+		for _, n := range result {
+			n.Mod(n, mod)
+		}
+	}
+	return result, nil
+}
+
+// abbreviate keeps huge numbers readable
+func abbreviate(n *big.Int) string {
+	s := n.String()
+	if len(s) <= 40 {
+		return s
+	}
+	return fmt.Sprintf("%s...%s (%d digits)", s[:12], s[len(s)-12:], len(s))
+}
+
+func printBigBuckets(buckets []*big.Int, t int, mod *big.Int) {
+	total := new(big.Int)
+	parts := make([]string, len(buckets))
+	for i, n := range buckets {
+		parts[i] = abbreviate(n)
+		total.Add(total, n)
+	}
+	if mod != nil {
+		total.Mod(total, mod)
+		fmt.Printf("t=%d (mod %v):\t%s\t -- total %s\n", t, mod, strings.Join(parts, "\t"), total)
+		return
+	}
+	fmt.Printf("t=%d:\t%s\t -- total %s\n", t, strings.Join(parts, "\t"), abbreviate(total))
+}
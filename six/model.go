@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// A Model describes a species of fish. A grown fish spawns every Cycle
+// days, and a newborn waits an extra Delay days before its first
+// spawn. If Lifespan is set, a fish dies on reaching that age in days.
+type Model struct {
+	Cycle    int
+	Delay    int
+	Lifespan int
+}
+
+// Lanternfish is the puzzle's model: spawn every 7 days, and newborns
+// start with their timer at 8
+var Lanternfish = Model{Cycle: 7, Delay: 2}
+
+func (m Model) Validate() error {
+	if m.Cycle < 1 || m.Delay < 0 || m.Lifespan < 0 {
+		return fmt.Errorf("cycle must be positive and delay and lifespan non-negative, not %+v", m)
+	}
+	return nil
+}
+
+// Timers is how many timer values a fish can have, so a newborn's
+// timer is Timers()-1 and a fish that has just spawned has Cycle-1
+func (m Model) Timers() int {
+	return m.Cycle + m.Delay
+}
+
+// ageOf is the youngest age a fish with the given timer can be. The
+// puzzle input only gives timers, so a fish with a lifespan is assumed
+// to be on its first cycle.
+func (m Model) ageOf(timer int) int {
+	return m.Timers() - 1 - timer
+}
+
+// timerOf is the timer of a fish of the given age
+func (m Model) timerOf(age int) int {
+	if age < m.Timers() {
+		return m.Timers() - 1 - age
+	}
+	return m.Cycle - 1 - (age-m.Timers())%m.Cycle
+}
+
+// A Population counts a Model's fish and advances them a day at a
+// time with the cursor trick from main: the buckets form a ring, and
+// rather than copy every bucket along each day, the cursor moves.
+//
+// Without a lifespan the ring holds one bucket per timer value and the
+// cursor marks timer 0. With a lifespan, fish have to be told apart by
+// age, so the ring holds one bucket per age and the cursor marks age 0.
+//
+// Counts start out as ints. The first day a count would overflow is
+// recorded in OverflowDay, and from then on the ring holds big.Ints.
+type Population struct {
+	Model       Model
+	Day         int
+	OverflowDay int
+
+	ring    []int
+	bigRing []*big.Int
+	cursor  int
+}
+
+// NewPopulation starts a population from counts by timer, as in the
+// puzzle input, where byTimer[i] fish have timer i
+func (m Model) NewPopulation(byTimer []int) (*Population, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	if len(byTimer) > m.Timers() {
+		return nil, fmt.Errorf("timers run from 0 to %d, but there are fish with timer %d",
+			m.Timers()-1, len(byTimer)-1)
+	}
+	p := &Population{Model: m}
+	if m.Lifespan == 0 {
+		p.ring = make([]int, m.Timers())
+		copy(p.ring, byTimer)
+		return p, nil
+	}
+	p.ring = make([]int, m.Lifespan)
+	for timer, n := range byTimer {
+		if age := m.ageOf(timer); age < m.Lifespan {
+			p.ring[age] += n
+		}
+	}
+	return p, nil
+}
+
+// Step advances the population by one day
+func (p *Population) Step() {
+	p.StepLimited(nil)
+}
+
+// StepLimited is Step with at most limit newborns, unless limit is nil
+func (p *Population) StepLimited(limit *big.Int) {
+	p.Day++
+	if p.bigRing != nil {
+		p.bigStep(limit)
+		return
+	}
+	size := len(p.ring)
+	if p.Model.Lifespan == 0 {
+		// the fish at timer 0 stay put to become their own newborns,
+		// and a copy of them joins the fish at timer Cycle-1
+		reset := (p.cursor + p.Model.Cycle) % size
+		if p.ring[reset] > math.MaxInt-p.ring[p.cursor] {
+			p.promote()
+			p.bigStep(limit)
+			return
+		}
+		parents := p.ring[p.cursor]
+		p.ring[p.cursor] = capBirths(parents, limit)
+		p.ring[reset] += parents
+		p.cursor = (p.cursor + 1) % size
+		return
+	}
+	// fish spawn on the days their timer is 0: first at age Timers-1,
+	// then every Cycle days after that
+	births := 0
+	for age := p.Model.Timers() - 1; age < size; age += p.Model.Cycle {
+		n := p.ring[(p.cursor+age)%size]
+		if births > math.MaxInt-n {
+			p.promote()
+			p.bigStep(limit)
+			return
+		}
+		births += n
+	}
+	// everyone ages a day, so the cursor moves back one and the oldest
+	// bucket, whose fish have just died, becomes age 0
+	p.cursor = (p.cursor + size - 1) % size
+	p.ring[p.cursor] = capBirths(births, limit)
+}
+
+func capBirths(births int, limit *big.Int) int {
+	if limit != nil && limit.Cmp(big.NewInt(int64(births))) < 0 {
+		return int(limit.Int64())
+	}
+	return births
+}
+
+// promote switches to big.Int counts on the day they are first needed
+func (p *Population) promote() {
+	p.OverflowDay = p.Day
+	p.bigRing = make([]*big.Int, len(p.ring))
+	for i, n := range p.ring {
+		p.bigRing[i] = big.NewInt(int64(n))
+	}
+	p.ring = nil
+}
+
+// bigStep is StepLimited for big.Int counts
+func (p *Population) bigStep(limit *big.Int) {
+	size := len(p.bigRing)
+	if p.Model.Lifespan == 0 {
+		parents := p.bigRing[p.cursor]
+		births := new(big.Int).Set(parents)
+		if limit != nil && limit.Cmp(births) < 0 {
+			births.Set(limit)
+		}
+		p.bigRing[p.cursor] = births
+		// with no Delay, the reset bucket is the newborns' own
+		reset := p.bigRing[(p.cursor+p.Model.Cycle)%size]
+		reset.Add(reset, parents)
+		p.cursor = (p.cursor + 1) % size
+		return
+	}
+	births := new(big.Int)
+	for age := p.Model.Timers() - 1; age < size; age += p.Model.Cycle {
+		births.Add(births, p.bigRing[(p.cursor+age)%size])
+	}
+	if limit != nil && limit.Cmp(births) < 0 {
+		births.Set(limit)
+	}
+	p.cursor = (p.cursor + size - 1) % size
+	p.bigRing[p.cursor] = births
+}
+
+func (p *Population) Advance(days int) {
+	for i := 0; i < days; i++ {
+		p.Step()
+	}
+}
+
+// Buckets returns the count of fish at each timer value. It panics if
+// the counts have outgrown ints; see BigBuckets.
+func (p *Population) Buckets() []int {
+	if p.bigRing != nil {
+		panic(fmt.Sprintf("fish counts overflowed an int on day %d", p.OverflowDay))
+	}
+	result := make([]int, p.Model.Timers())
+	for i := range p.ring {
+		result[p.timerAt(i)] += p.ring[(p.cursor+i)%len(p.ring)]
+	}
+	return result
+}
+
+// BigBuckets is Buckets with exact counts, however large
+func (p *Population) BigBuckets() []*big.Int {
+	result := make([]*big.Int, p.Model.Timers())
+	for i := range result {
+		result[i] = new(big.Int)
+	}
+	size := len(p.ring)
+	if p.bigRing != nil {
+		size = len(p.bigRing)
+	}
+	for i := 0; i < size; i++ {
+		n := result[p.timerAt(i)]
+		if p.bigRing != nil {
+			n.Add(n, p.bigRing[(p.cursor+i)%size])
+		} else {
+			n.Add(n, big.NewInt(int64(p.ring[(p.cursor+i)%size])))
+		}
+	}
+	return result
+}
+
+// timerAt is the timer value of the fish i buckets after the cursor
+func (p *Population) timerAt(i int) int {
+	if p.Model.Lifespan == 0 {
+		return i
+	}
+	return p.Model.timerOf(i)
+}
+
+// Total is exact however large the population gets
+func (p *Population) Total() *big.Int {
+	total := new(big.Int)
+	for _, n := range p.BigBuckets() {
+		total.Add(total, n)
+	}
+	return total
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+// the puzzle's example, 3,4,3,1,2, counted by timer
+var example = []int{0, 1, 1, 2, 1}
+
+// models to check the algorithms against each other with, including
+// ones where newborns spawn straight away and ones where fish die
+var models = []Model{
+	Lanternfish,
+	{Cycle: 7, Delay: 0},
+	{Cycle: 3, Delay: 2, Lifespan: 20},
+	{Cycle: 7, Delay: 2, Lifespan: 30},
+}
+
+func TestExample(t *testing.T) {
+	for _, c := range []struct {
+		days int
+		want string
+	}{
+		{18, "26"},
+		{80, "5934"},
+		{256, "26984457539"},
+	} {
+		pop, err := Lanternfish.NewPopulation(example)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pop.Advance(c.days)
+		if got := pop.Total().String(); got != c.want {
+			t.Errorf("after %d days the population model has %s fish, want %s", c.days, got, c.want)
+		}
+		if got := sum(simulateSlow(example, c.days)); got != c.want {
+			t.Errorf("after %d days the 'slow' way has %s fish, want %s", c.days, got, c.want)
+		}
+	}
+}
+
+func sum(buckets []int) string {
+	total := new(big.Int)
+	for _, n := range buckets {
+		total.Add(total, big.NewInt(int64(n)))
+	}
+	return total.String()
+}
+
+func TestProjectMatchesPopulation(t *testing.T) {
+	mod := big.NewInt(1_000_000_007)
+	for _, m := range models {
+		pop, err := m.NewPopulation(example)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for day := 0; day <= 300; day++ {
+			projected, err := m.Project(example, day, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reduced, err := m.Project(example, day, mod)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, n := range pop.BigBuckets() {
+				if projected[i].Cmp(n) != 0 {
+					t.Fatalf("%+v, day %d, timer %d: projected %v, population model %v",
+						m, day, i, projected[i], n)
+				}
+				if want := new(big.Int).Mod(n, mod); reduced[i].Cmp(want) != 0 {
+					t.Fatalf("%+v, day %d, timer %d: projected %v mod %v, want %v",
+						m, day, i, reduced[i], mod, want)
+				}
+			}
+			pop.Step()
+		}
+		// and once far enough out that the counts outgrow an int
+		pop.Advance(2000 - pop.Day)
+		projected, err := m.Project(example, pop.Day, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, n := range pop.BigBuckets() {
+			if projected[i].Cmp(n) != 0 {
+				t.Fatalf("%+v, day %d, timer %d: projected %v, population model %v",
+					m, pop.Day, i, projected[i], n)
+			}
+		}
+	}
+}
+
+func TestAgentsMatchPopulation(t *testing.T) {
+	for _, m := range models {
+		pop, err := m.NewPopulation(example)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for day := 0; day <= 60; day++ {
+			agents, err := m.SimulateAgents(example, day, AgentOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := pop.Buckets(); !sameBuckets(agents, want) {
+				t.Fatalf("%+v, day %d: per-fish simulation gives %v, population model %v",
+					m, day, agents, want)
+			}
+			pop.Step()
+		}
+	}
+}
+
+func sameBuckets(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// An Ecosystem is several species of fish in the same water. Each
+// species has its own Model, and rules let one species hold another's
+// births back. A species definition file looks like
+//
+//	# name cycle delay [lifespan]
+//	species lanternfish 7 2
+//	species eel 5 3 40
+//	# each day, eel births are at most 1/10 of the lanternfish
+//	cap eel by lanternfish 1/10
+//
+// where the ratio of a cap rule defaults to 1.
+type Ecosystem struct {
+	Species []Species
+	Rules   []Rule
+}
+
+type Species struct {
+	Name  string
+	Model Model
+}
+
+// A Rule caps the births of the Capped species each day at Ratio times
+// the count of the By species at the start of the day
+type Rule struct {
+	Capped, By string
+	Ratio      *big.Rat
+}
+
+func (e *Ecosystem) species(name string) int {
+	for i, s := range e.Species {
+		if s.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// ParseEcosystem reads a species definition file
+func ParseEcosystem(r io.Reader) (*Ecosystem, error) {
+	e := &Ecosystem{}
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		if err := e.parseLine(scanner.Text()); err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(e.Species) == 0 {
+		return nil, fmt.Errorf("no species defined")
+	}
+	return e, nil
+}
+
+func (e *Ecosystem) parseLine(line string) error {
+	if i := strings.Index(line, "#"); i >= 0 {
+		line = line[:i]
+	}
+	f := strings.Fields(line)
+	if len(f) == 0 {
+		return nil
+	}
+	switch f[0] {
+	case "species":
+		if len(f) != 4 && len(f) != 5 {
+			return fmt.Errorf("expected: species name cycle delay [lifespan]")
+		}
+		if e.species(f[1]) >= 0 {
+			return fmt.Errorf("species %q is already defined", f[1])
+		}
+		var nums []int
+		for _, s := range f[2:] {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return fmt.Errorf("%q is not an integer", s)
+			}
+			nums = append(nums, n)
+		}
+		m := Model{Cycle: nums[0], Delay: nums[1]}
+		if len(nums) == 3 {
+			m.Lifespan = nums[2]
+		}
+		if err := m.Validate(); err != nil {
+			return err
+		}
+		e.Species = append(e.Species, Species{Name: f[1], Model: m})
+	case "cap":
+		if (len(f) != 4 && len(f) != 5) || f[2] != "by" {
+			return fmt.Errorf("expected: cap species by species [ratio]")
+		}
+		rule := Rule{Capped: f[1], By: f[3], Ratio: big.NewRat(1, 1)}
+		for _, name := range []string{rule.Capped, rule.By} {
+			if e.species(name) < 0 {
+				return fmt.Errorf("species %q has not been defined", name)
+			}
+		}
+		if len(f) == 5 {
+			if _, ok := rule.Ratio.SetString(f[4]); !ok || rule.Ratio.Sign() < 0 {
+				return fmt.Errorf("%q is not a non-negative ratio", f[4])
+			}
+		}
+		e.Rules = append(e.Rules, rule)
+	default:
+		return fmt.Errorf("unknown definition %q", f[0])
+	}
+	return nil
+}
+
+// ReadTimers reads the starting fish, one species per line, as
+//
+//	lanternfish: 3,4,3,1,2
+//
+// Lines for the same species add up. A line with no name is taken to be
+// the first species, so plain puzzle input still works.
+func (e *Ecosystem) ReadTimers(r io.Reader) (map[string][]int, error) {
+	result := make(map[string][]int)
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		name := e.Species[0].Name
+		if i := strings.Index(line, ":"); i >= 0 {
+			name, line = strings.TrimSpace(line[:i]), line[i+1:]
+			if e.species(name) < 0 {
+				return nil, fmt.Errorf("line %d: species %q has not been defined", lineNo, name)
+			}
+		}
+		buckets := result[name]
+		for _, s := range strings.Split(line, ",") {
+			timer, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil || timer < 0 {
+				return nil, fmt.Errorf("line %d: %q is not a timer", lineNo, s)
+			}
+			for len(buckets) <= timer {
+				buckets = append(buckets, 0)
+			}
+			buckets[timer]++
+		}
+		result[name] = buckets
+	}
+	return result, scanner.Err()
+}
+
+// A Community is an Ecosystem's populations, advancing together
+type Community struct {
+	Ecosystem *Ecosystem
+	Day       int
+	Pops      []*Population // in the order of Ecosystem.Species
+}
+
+func (e *Ecosystem) NewCommunity(byTimer map[string][]int) (*Community, error) {
+	c := &Community{Ecosystem: e}
+	for _, s := range e.Species {
+		pop, err := s.Model.NewPopulation(byTimer[s.Name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", s.Name, err)
+		}
+		c.Pops = append(c.Pops, pop)
+	}
+	return c, nil
+}
+
+// Step advances every species by a day. The caps all use the counts
+// from the start of the day, so the order of the species doesn't
+// matter.
+func (c *Community) Step() {
+	totals := make([]*big.Int, len(c.Pops))
+	for i, pop := range c.Pops {
+		totals[i] = pop.Total()
+	}
+	limits := make([]*big.Int, len(c.Pops))
+	for _, rule := range c.Ecosystem.Rules {
+		capped := c.Ecosystem.species(rule.Capped)
+		by := totals[c.Ecosystem.species(rule.By)]
+		limit := new(big.Int).Mul(by, rule.Ratio.Num())
+		limit.Quo(limit, rule.Ratio.Denom())
+		if limits[capped] == nil || limit.Cmp(limits[capped]) < 0 {
+			limits[capped] = limit
+		}
+	}
+	for i, pop := range c.Pops {
+		pop.StepLimited(limits[i])
+	}
+	c.Day++
+}
+
+func (c *Community) Advance(days int) {
+	for i := 0; i < days; i++ {
+		c.Step()
+	}
+}
+
+func (c *Community) Print() {
+	for i, s := range c.Ecosystem.Species {
+		fmt.Printf("%s ", s.Name)
+		printBigBuckets(c.Pops[i].BigBuckets(), c.Day, nil)
+	}
+}
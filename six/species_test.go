@@ -0,0 +1,158 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+const exampleSpecies = `
+# name cycle delay [lifespan]
+species lanternfish 7 2
+species eel 5 3 40   # eels die
+cap eel by lanternfish 1/10
+cap lanternfish by eel
+`
+
+func TestParseEcosystem(t *testing.T) {
+	e, err := ParseEcosystem(strings.NewReader(exampleSpecies))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Species{
+		{"lanternfish", Lanternfish},
+		{"eel", Model{Cycle: 5, Delay: 3, Lifespan: 40}},
+	}
+	if len(e.Species) != len(want) {
+		t.Fatalf("got species %+v, want %+v", e.Species, want)
+	}
+	for i, s := range want {
+		if e.Species[i] != s {
+			t.Errorf("species %d is %+v, want %+v", i, e.Species[i], s)
+		}
+	}
+	if len(e.Rules) != 2 {
+		t.Fatalf("got rules %+v, want 2", e.Rules)
+	}
+	if r := e.Rules[0]; r.Capped != "eel" || r.By != "lanternfish" || r.Ratio.Cmp(big.NewRat(1, 10)) != 0 {
+		t.Errorf("first rule is %v by %v at %v, want eel by lanternfish at 1/10", r.Capped, r.By, r.Ratio)
+	}
+	if r := e.Rules[1]; r.Ratio.Cmp(big.NewRat(1, 1)) != 0 {
+		t.Errorf("a rule with no ratio has ratio %v, want 1", r.Ratio)
+	}
+}
+
+func TestParseEcosystemErrors(t *testing.T) {
+	for _, c := range []struct {
+		src, want string
+	}{
+		{"", "no species defined"},
+		{"# just a comment\n", "no species defined"},
+		{"species eel 5\n", "line 1: expected: species"},
+		{"species eel 5 x\n", `line 1: "x" is not an integer`},
+		{"species eel 0 3\n", "line 1: cycle must be positive"},
+		{"species eel 5 3\nspecies eel 6 1\n", `line 2: species "eel" is already defined`},
+		{"species eel 5 3\ncap eel by shark\n", `line 2: species "shark" has not been defined`},
+		{"species eel 5 3\ncap eel over eel\n", "line 2: expected: cap"},
+		{"species eel 5 3\ncap eel by eel -1/2\n", `line 2: "-1/2" is not a non-negative ratio`},
+		{"species eel 5 3\nfeed eel\n", `line 2: unknown definition "feed"`},
+	} {
+		_, err := ParseEcosystem(strings.NewReader(c.src))
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%q: got error %v, want one containing %q", c.src, err, c.want)
+		}
+	}
+}
+
+func TestReadTimers(t *testing.T) {
+	e, err := ParseEcosystem(strings.NewReader(exampleSpecies))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byTimer, err := e.ReadTimers(strings.NewReader("3,4\n\neel: 1, 1\n 3 \n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := byTimer["lanternfish"], []int{0, 0, 0, 2, 1}; !sameBuckets(got, want) {
+		t.Errorf("lanternfish: got %v, want %v", got, want)
+	}
+	if got, want := byTimer["eel"], []int{0, 2}; !sameBuckets(got, want) {
+		t.Errorf("eel: got %v, want %v", got, want)
+	}
+
+	for _, c := range []struct {
+		src, want string
+	}{
+		{"shark: 1,2\n", `line 1: species "shark" has not been defined`},
+		{"3,4\neel: 1,x\n", `line 2: "x" is not a timer`},
+		{"3,-1\n", `line 1: "-1" is not a timer`},
+	} {
+		_, err := e.ReadTimers(strings.NewReader(c.src))
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%q: got error %v, want one containing %q", c.src, err, c.want)
+		}
+	}
+}
+
+func TestCapLimitsBirths(t *testing.T) {
+	e, err := ParseEcosystem(strings.NewReader(`
+species lanternfish 7 2
+species eel 5 3
+cap eel by lanternfish 1/10
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 25 lanternfish allow 2 eel births, but 5 eels are about to spawn
+	c, err := e.NewCommunity(map[string][]int{
+		"lanternfish": {0, 0, 0, 0, 0, 25},
+		"eel":         {5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Step()
+	eels := c.Pops[1].Buckets()
+	if newborns := eels[len(eels)-1]; newborns != 2 {
+		t.Errorf("%d eels were born, want the cap of 2", newborns)
+	}
+	if parents := eels[4]; parents != 5 {
+		t.Errorf("%d eels restarted their cycle, want all 5", parents)
+	}
+	// the lanternfish aren't capped
+	if got, want := c.Pops[0].Buckets(), []int{0, 0, 0, 0, 25, 0, 0, 0, 0}; !sameBuckets(got, want) {
+		t.Errorf("lanternfish: got %v, want %v", got, want)
+	}
+}
+
+func TestSingleSpeciesMatchesLanternfish(t *testing.T) {
+	e, err := ParseEcosystem(strings.NewReader("species lanternfish 7 2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byTimer, err := e.ReadTimers(strings.NewReader("3,4,3,1,2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := e.NewCommunity(byTimer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pop, err := Lanternfish.NewPopulation(example)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for day := 0; ; day++ {
+		if got, want := c.Pops[0].Buckets(), pop.Buckets(); !sameBuckets(got, want) {
+			t.Fatalf("day %d: community has %v, Lanternfish has %v", day, got, want)
+		}
+		if day == 256 {
+			break
+		}
+		c.Step()
+		pop.Step()
+	}
+	if got := c.Pops[0].Total().String(); got != "26984457539" {
+		t.Errorf("after 256 days the community has %s fish, want 26984457539", got)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+)
+
+// A Snapshot is the population on one day of a timeline
+type Snapshot struct {
+	Day     int        `json:"day"`
+	Buckets []*big.Int `json:"buckets"`
+	Total   *big.Int   `json:"total"`
+}
+
+// Timeline runs a population from byTimer for the given number of days,
+// keeping every day's buckets, day 0 included
+func (m Model) Timeline(byTimer []int, days int) ([]Snapshot, error) {
+	if days < 0 {
+		return nil, fmt.Errorf("cannot run a timeline %d days into the past", days)
+	}
+	pop, err := m.NewPopulation(byTimer)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Snapshot, 0, days+1)
+	for {
+		buckets := pop.BigBuckets()
+		total := new(big.Int)
+		for _, n := range buckets {
+			total.Add(total, n)
+		}
+		result = append(result, Snapshot{Day: pop.Day, Buckets: buckets, Total: total})
+		if pop.Day == days {
+			return result, nil
+		}
+		pop.Step()
+	}
+}
+
+// WriteTimelineCSV writes one row per day: the day, the count at each
+// timer value, then the total
+func WriteTimelineCSV(w io.Writer, timeline []Snapshot) error {
+	out := csv.NewWriter(w)
+	if len(timeline) > 0 {
+		header := []string{"day"}
+		for i := range timeline[0].Buckets {
+			header = append(header, "timer"+strconv.Itoa(i))
+		}
+		out.Write(append(header, "total"))
+	}
+	for _, s := range timeline {
+		row := []string{strconv.Itoa(s.Day)}
+		for _, n := range s.Buckets {
+			row = append(row, n.String())
+		}
+		out.Write(append(row, s.Total.String()))
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// WriteTimelineJSON writes the timeline as an array of snapshots. The
+// counts are plain JSON numbers, however many digits they run to.
+func WriteTimelineJSON(w io.Writer, timeline []Snapshot) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(timeline)
+}
+
+// FirstDayExceeding returns the first day on which the population is
+// larger than n, looking no further than limit days. Without a lifespan
+// the population never shrinks and grows exponentially, so this is a
+// short walk even for huge n; with one it may die out first, and ok is false.
+func (m Model) FirstDayExceeding(byTimer []int, n *big.Int, limit int) (day int, ok bool, err error) {
+	pop, err := m.NewPopulation(byTimer)
+	if err != nil {
+		return 0, false, err
+	}
+	for {
+		total := pop.Total()
+		if total.Cmp(n) > 0 {
+			return pop.Day, true, nil
+		}
+		if pop.Day >= limit || total.Sign() == 0 {
+			return 0, false, nil
+		}
+		pop.Step()
+	}
+}
+
+// BestStart finds the starting distribution of k fish that has the
+// largest population after the given number of days. Every fish's
+// descendants grow independently of everyone else's, so the population
+// is a weighted sum of the starting counts, with one weight per timer
+// value: the descendants of a single fish with that timer. All k fish
+// should therefore share the timer with the largest weight, and ties go
+// to the smallest timer.
+func (m Model) BestStart(k, days int) (byTimer []int, total *big.Int, err error) {
+	if k < 0 {
+		return nil, nil, fmt.Errorf("cannot start with %d fish", k)
+	}
+	best := -1
+	var bestWeight *big.Int
+	for timer := 0; timer < m.Timers(); timer++ {
+		one := make([]int, timer+1)
+		one[timer] = 1
+		pop, err := m.NewPopulation(one)
+		if err != nil {
+			return nil, nil, err
+		}
+		pop.Advance(days)
+		if weight := pop.Total(); best < 0 || weight.Cmp(bestWeight) > 0 {
+			best, bestWeight = timer, weight
+		}
+	}
+	byTimer = make([]int, m.Timers())
+	byTimer[best] = k
+	return byTimer, new(big.Int).Mul(bestWeight, big.NewInt(int64(k))), nil
+}
+
+func writeFile(name string, write func(io.Writer) error) error {
+	if name == "" {
+		return nil
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,17 @@
+package main
+
+import "testing"
+
+func TestTimeline(t *testing.T) {
+	timeline, err := Lanternfish.Timeline(example, 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(timeline) != 19 || timeline[18].Day != 18 || timeline[18].Total.String() != "26" {
+		t.Errorf("timeline ends with %+v after %d snapshots, want 26 fish on day 18",
+			timeline[len(timeline)-1], len(timeline))
+	}
+	if _, err := Lanternfish.Timeline(example, -1); err == nil {
+		t.Error("a timeline into the past should be an error")
+	}
+}